@@ -8,7 +8,10 @@
 // create new versions.
 package ordmap
 
-import "iter"
+import (
+	"iter"
+	"slices"
+)
 
 // Comparable is an interface for types that can be compared.
 // It requires a Less method that returns true if the receiver is less than the argument.
@@ -29,6 +32,16 @@ type Builtin[A BuiltinComparable] struct {
 	value A
 }
 
+// MakeBuiltin wraps value as a key of a Node, e.g. for the Node of a NodeBuiltin.
+func MakeBuiltin[A BuiltinComparable](value A) Builtin[A] {
+	return Builtin[A]{value}
+}
+
+// Value returns the wrapped value.
+func (b Builtin[A]) Value() A {
+	return b.value
+}
+
 // Less compares two Builtin values using the < operator.
 func (b Builtin[A]) Less(b2 Builtin[A]) bool {
 	return b.value < b2.value
@@ -50,12 +63,115 @@ type Entry[K, V any] struct {
 	V V
 }
 
+// Bound describes how one end of a range is treated.
+type Bound uint8
+
+const (
+	// Inclusive bounds include the boundary key itself.
+	Inclusive Bound = iota
+	// Exclusive bounds exclude the boundary key.
+	Exclusive
+	// Unbounded ends ignore the boundary key altogether.
+	Unbounded
+)
+
+// RangeOptions configures both ends of a range.
+// The zero value describes the closed interval [lo, hi].
+type RangeOptions struct {
+	Lo Bound
+	Hi Bound
+}
+
+// FromSorted builds a perfectly balanced map from entries sorted by strictly ascending key.
+// The ordering precondition is not checked.
+// Runs in O(N), as opposed to O(N log N) for repeated Insert.
+func FromSorted[K Comparable[K], V any](entries []Entry[K, V]) *Node[K, V] {
+	return asNode(nodeCore[K, V]().fromSorted(entries))
+}
+
+// FromSortedSeq builds a perfectly balanced map from a sequence sorted by strictly ascending key.
+// The ordering precondition is not checked.
+// Runs in O(N).
+func FromSortedSeq[K Comparable[K], V any](seq iter.Seq2[K, V]) *Node[K, V] {
+	var entries []Entry[K, V]
+	for k, v := range seq {
+		entries = append(entries, Entry[K, V]{k, v})
+	}
+	return FromSorted(entries)
+}
+
+// FromSeq builds a map from a sequence in arbitrary order.
+// If a key occurs multiple times, the last value wins.
+// The entries are collected into a slice and sorted, then the tree is built once,
+// so unlike repeated Insert no intermediate trees are allocated.
+// Runs in O(N log N), or in O(N) if the sequence happens to be sorted already.
+func FromSeq[K Comparable[K], V any](seq iter.Seq2[K, V]) *Node[K, V] {
+	var entries []Entry[K, V]
+	for k, v := range seq {
+		entries = append(entries, Entry[K, V]{k, v})
+	}
+	return fromEntries(entries)
+}
+
+// fromEntries builds a map from entries in arbitrary order, sorting them in place.
+func fromEntries[K Comparable[K], V any](entries []Entry[K, V]) *Node[K, V] {
+	return asNode(nodeCore[K, V]().fromEntries(entries))
+}
+
+// FromSortedBuiltin builds a perfectly balanced map from entries sorted by strictly ascending key.
+// See FromSorted for details.
+func FromSortedBuiltin[K BuiltinComparable, V any](entries []Entry[K, V]) NodeBuiltin[K, V] {
+	return NodeBuiltin[K, V]{FromSorted(wrapEntries(entries))}
+}
+
+// FromSortedSeqBuiltin builds a perfectly balanced map from a sequence sorted by strictly ascending key.
+// See FromSortedSeq for details.
+func FromSortedSeqBuiltin[K BuiltinComparable, V any](seq iter.Seq2[K, V]) NodeBuiltin[K, V] {
+	return NodeBuiltin[K, V]{FromSortedSeq(wrapSeq(seq))}
+}
+
+// FromSeqBuiltin builds a map from a sequence in arbitrary order.
+// See FromSeq for details.
+func FromSeqBuiltin[K BuiltinComparable, V any](seq iter.Seq2[K, V]) NodeBuiltin[K, V] {
+	return NodeBuiltin[K, V]{FromSeq(wrapSeq(seq))}
+}
+
+func wrapEntries[K BuiltinComparable, V any](entries []Entry[K, V]) []Entry[Builtin[K], V] {
+	wrapped := make([]Entry[Builtin[K], V], len(entries))
+	for i, e := range entries {
+		wrapped[i] = Entry[Builtin[K], V]{Builtin[K]{e.K}, e.V}
+	}
+	return wrapped
+}
+
+func wrapSeq[K BuiltinComparable, V any](seq iter.Seq2[K, V]) iter.Seq2[Builtin[K], V] {
+	return func(yield func(Builtin[K], V) bool) {
+		for k, v := range seq {
+			if !yield(Builtin[K]{k}, v) {
+				return
+			}
+		}
+	}
+}
+
 // NodeBuiltin is a wrapper around Node for built-in comparable types.
 // It simplifies usage by handling the Builtin wrapper automatically.
 type NodeBuiltin[K BuiltinComparable, V any] struct {
 	n *Node[Builtin[K], V]
 }
 
+// WrapBuiltin returns a NodeBuiltin backed by node without copying it.
+func WrapBuiltin[K BuiltinComparable, V any](node *Node[Builtin[K], V]) NodeBuiltin[K, V] {
+	return NodeBuiltin[K, V]{node}
+}
+
+// Node returns the underlying *Node, which shares all structure with the map.
+// This gives access to functions that only accept a *Node.
+// Its keys are wrapped, see MakeBuiltin and Builtin.Value.
+func (n NodeBuiltin[K, V]) Node() *Node[Builtin[K], V] {
+	return n.n
+}
+
 // Get retrieves the value for the given key.
 // It returns the value and true if the key exists, otherwise the zero value and false.
 func (n NodeBuiltin[K, V]) Get(key K) (value V, ok bool) {
@@ -69,6 +185,21 @@ func (n NodeBuiltin[K, V]) Insert(key K, value V) NodeBuiltin[K, V] {
 	return NodeBuiltin[K, V]{n.n.Insert(Builtin[K]{key}, value)}
 }
 
+// Update inserts, modifies or deletes the entry for key in a single descent.
+// fn receives the current value (or the zero value) and whether the key exists.
+// It returns the new value and whether the entry should be kept in the map.
+// If the key does not exist and fn does not keep it, the map is returned unchanged.
+// Returns a new map containing the change.
+func (n NodeBuiltin[K, V]) Update(key K, fn func(old V, exists bool) (V, bool)) NodeBuiltin[K, V] {
+	return NodeBuiltin[K, V]{n.n.Update(Builtin[K]{key}, fn)}
+}
+
+// InsertFunc adds a key-value pair to the map like Insert, but if the key already
+// exists and eq reports the old and new values as equal, the map is returned unchanged.
+func (n NodeBuiltin[K, V]) InsertFunc(key K, value V, eq func(old, new V) bool) NodeBuiltin[K, V] {
+	return NodeBuiltin[K, V]{n.n.InsertFunc(Builtin[K]{key}, value, eq)}
+}
+
 // Remove deletes the key from the map.
 // If the key does not exist, the map is returned unchanged.
 // Returns a new map containing the change.
@@ -76,18 +207,76 @@ func (n NodeBuiltin[K, V]) Remove(key K) NodeBuiltin[K, V] {
 	return NodeBuiltin[K, V]{n.n.Remove(Builtin[K]{key})}
 }
 
+// Split partitions the map around key.
+// left contains all keys less than key and right all keys greater than key.
+// If key is present, its value is returned and found is true.
+// Runs in O(log N) and shares structure with the original map.
+func (n NodeBuiltin[K, V]) Split(key K) (left NodeBuiltin[K, V], value V, found bool, right NodeBuiltin[K, V]) {
+	l, value, found, r := n.n.Split(Builtin[K]{key})
+	return NodeBuiltin[K, V]{l}, value, found, NodeBuiltin[K, V]{r}
+}
+
+// JoinBuiltin concatenates two maps where all keys in left are less than all keys in right.
+// Runs in O(log N) and shares structure with both inputs.
+func JoinBuiltin[K BuiltinComparable, V any](left, right NodeBuiltin[K, V]) NodeBuiltin[K, V] {
+	return NodeBuiltin[K, V]{Join(left.n, right.n)}
+}
+
+func unwrapResolve[K BuiltinComparable, V any](resolve func(k K, va, vb V) V) func(Builtin[K], V, V) V {
+	if resolve == nil {
+		return nil
+	}
+	return func(k Builtin[K], va, vb V) V {
+		return resolve(k.value, va, vb)
+	}
+}
+
+// UnionBuiltin returns a map containing all keys from a and b.
+// See Union for details.
+func UnionBuiltin[K BuiltinComparable, V any](a, b NodeBuiltin[K, V], resolve func(k K, va, vb V) V) NodeBuiltin[K, V] {
+	return NodeBuiltin[K, V]{Union(a.n, b.n, unwrapResolve(resolve))}
+}
+
+// IntersectionBuiltin returns a map containing only keys present in both a and b.
+// See Intersection for details.
+func IntersectionBuiltin[K BuiltinComparable, V any](a, b NodeBuiltin[K, V], resolve func(k K, va, vb V) V) NodeBuiltin[K, V] {
+	return NodeBuiltin[K, V]{Intersection(a.n, b.n, unwrapResolve(resolve))}
+}
+
+// DifferenceBuiltin returns a map containing the entries of a whose keys are not present in b.
+// See Difference for details.
+func DifferenceBuiltin[K BuiltinComparable, V any](a, b NodeBuiltin[K, V]) NodeBuiltin[K, V] {
+	return NodeBuiltin[K, V]{Difference(a.n, b.n)}
+}
+
+// RemoveRange deletes all keys between lo and hi.
+// See Node.RemoveRange for details.
+func (n NodeBuiltin[K, V]) RemoveRange(lo, hi K, opts RangeOptions) NodeBuiltin[K, V] {
+	return NodeBuiltin[K, V]{n.n.RemoveRange(Builtin[K]{lo}, Builtin[K]{hi}, opts)}
+}
+
+// TruncateBefore deletes all keys strictly less than key.
+func (n NodeBuiltin[K, V]) TruncateBefore(key K) NodeBuiltin[K, V] {
+	return NodeBuiltin[K, V]{n.n.TruncateBefore(Builtin[K]{key})}
+}
+
+// TruncateAfter deletes all keys strictly greater than key.
+func (n NodeBuiltin[K, V]) TruncateAfter(key K) NodeBuiltin[K, V] {
+	return NodeBuiltin[K, V]{n.n.TruncateAfter(Builtin[K]{key})}
+}
+
 // Len returns the number of elements in the map.
 func (n NodeBuiltin[K, V]) Len() int {
 	return n.n.Len()
 }
 
 // Entries returns a slice of all key-value pairs in the map, sorted by key.
+// Each key is unwrapped on the way, which NodeOrdered, storing keys as they are,
+// does not need to do.
 func (n NodeBuiltin[K, V]) Entries() []Entry[K, V] {
-	baseEntries := n.n.Entries()
-	// TODO can this be unsafely cast?
-	entries := make([]Entry[K, V], len(baseEntries))
-	for i, e := range baseEntries {
-		entries[i] = Entry[K, V]{e.K.value, e.V}
+	entries := make([]Entry[K, V], 0, n.Len())
+	for k, v := range n.n.All() {
+		entries = append(entries, Entry[K, V]{k.value, v})
 	}
 	return entries
 }
@@ -112,6 +301,80 @@ func (n NodeBuiltin[K, V]) Max() *Entry[K, V] {
 	return &Entry[K, V]{e.K.value, e.V}
 }
 
+// PopMin removes the entry with the smallest key in a single descent.
+// Returns the removed entry and the new map, or nil and the unchanged map if it is empty.
+func (n NodeBuiltin[K, V]) PopMin() (*Entry[K, V], NodeBuiltin[K, V]) {
+	e, rest := n.n.PopMin()
+	return unwrapEntry(e), NodeBuiltin[K, V]{rest}
+}
+
+// PopMax removes the entry with the largest key in a single descent.
+// Returns the removed entry and the new map, or nil and the unchanged map if it is empty.
+func (n NodeBuiltin[K, V]) PopMax() (*Entry[K, V], NodeBuiltin[K, V]) {
+	e, rest := n.n.PopMax()
+	return unwrapEntry(e), NodeBuiltin[K, V]{rest}
+}
+
+// DeleteMin removes the entry with the smallest key.
+// Returns a new map containing the change.
+func (n NodeBuiltin[K, V]) DeleteMin() NodeBuiltin[K, V] {
+	return NodeBuiltin[K, V]{n.n.DeleteMin()}
+}
+
+// DeleteMax removes the entry with the largest key.
+// Returns a new map containing the change.
+func (n NodeBuiltin[K, V]) DeleteMax() NodeBuiltin[K, V] {
+	return NodeBuiltin[K, V]{n.n.DeleteMax()}
+}
+
+func unwrapEntry[K BuiltinComparable, V any](e *Entry[Builtin[K], V]) *Entry[K, V] {
+	if e == nil {
+		return nil
+	}
+	return &Entry[K, V]{e.K.value, e.V}
+}
+
+// Floor returns the entry with the greatest key less than or equal to key.
+// Returns nil if there is no such entry.
+func (n NodeBuiltin[K, V]) Floor(key K) *Entry[K, V] {
+	return unwrapEntry(n.n.Floor(Builtin[K]{key}))
+}
+
+// Ceiling returns the entry with the least key greater than or equal to key.
+// Returns nil if there is no such entry.
+func (n NodeBuiltin[K, V]) Ceiling(key K) *Entry[K, V] {
+	return unwrapEntry(n.n.Ceiling(Builtin[K]{key}))
+}
+
+// Lower returns the entry with the greatest key strictly less than key.
+// Returns nil if there is no such entry.
+func (n NodeBuiltin[K, V]) Lower(key K) *Entry[K, V] {
+	return unwrapEntry(n.n.Lower(Builtin[K]{key}))
+}
+
+// Higher returns the entry with the least key strictly greater than key.
+// Returns nil if there is no such entry.
+func (n NodeBuiltin[K, V]) Higher(key K) *Entry[K, V] {
+	return unwrapEntry(n.n.Higher(Builtin[K]{key}))
+}
+
+// At returns the entry at position i in key order (0-based).
+// Returns nil if i is out of range.
+func (n NodeBuiltin[K, V]) At(i int) *Entry[K, V] {
+	return unwrapEntry(n.n.At(i))
+}
+
+// IndexOf returns the position of key in key order (0-based) and true if the key exists,
+// otherwise 0 and false.
+func (n NodeBuiltin[K, V]) IndexOf(key K) (int, bool) {
+	return n.n.IndexOf(Builtin[K]{key})
+}
+
+// Rank returns the number of keys in the map that are strictly less than key.
+func (n NodeBuiltin[K, V]) Rank(key K) int {
+	return n.n.Rank(Builtin[K]{key})
+}
+
 // All returns an iterator over all key-value pairs in the map, sorted by key (ascending).
 func (n NodeBuiltin[K, V]) All() iter.Seq2[K, V] {
 	return func(yield func(K, V) bool) {
@@ -160,369 +423,1264 @@ func (n NodeBuiltin[K, V]) BackwardFrom(k K) iter.Seq2[K, V] {
 
 // Node represents a node in the AVL tree, which also serves as the map handle.
 // A nil *Node represents an empty map.
-type Node[K Comparable[K], V any] struct {
-	entry    Entry[K, V]
-	h        int
-	len      int
-	children [2]*Node[K, V]
+type Node[K Comparable[K], V any] tree[K, V, struct{}]
+
+// tree returns the node as a tree of the shared core, which is a free conversion.
+func (node *Node[K, V]) tree() *tree[K, V, struct{}] {
+	return (*tree[K, V, struct{}])(node)
+}
+
+func asNode[K Comparable[K], V any](t *tree[K, V, struct{}]) *Node[K, V] {
+	return (*Node[K, V])(t)
+}
+
+// child returns the left (dir 0) or right (dir 1) subtree of a non-empty map.
+func (node *Node[K, V]) child(dir int) *Node[K, V] {
+	return asNode(node.children[dir])
 }
 
 func (node *Node[K, V]) height() int {
-	if node == nil {
-		return 0
-	}
-	return node.h
+	return node.tree().height()
+}
+
+func mk_OrdMap[K Comparable[K], V any](entry Entry[K, V], left, right *Node[K, V]) *Node[K, V] {
+	return asNode(nodeCore[K, V]().mk(entry, left.tree(), right.tree()))
 }
 
-func combinedDepth[K Comparable[K], V any](n1, n2 *Node[K, V]) int {
-	d1 := n1.height()
-	d2 := n2.height()
-	var d int
-	if d1 > d2 {
-		d = d1
-	} else {
-		d = d2
+// lessOrder orders the keys of a Node by their Less method.
+type lessOrder[K Comparable[K], V, A any] struct{}
+
+func (lessOrder[K, V, A]) compare(a, b K) int {
+	if a.Less(b) {
+		return -1
 	}
-	return d + 1
+	if b.Less(a) {
+		return 1
+	}
+	return 0
 }
 
-func mk_OrdMap[K Comparable[K], V any](entry Entry[K, V], left, right *Node[K, V]) *Node[K, V] {
-	len := 1
-	if left != nil {
-		len += left.len
+func (lessOrder[K, V, A]) find(node *tree[K, V, A], key K) *tree[K, V, A] {
+	for node != nil {
+		if key.Less(node.entry.K) {
+			node = node.children[0]
+		} else if node.entry.K.Less(key) {
+			node = node.children[1]
+		} else {
+			return node
+		}
 	}
-	if right != nil {
-		len += right.len
+	return nil
+}
+
+// search compares only once per level, going right at key and leaving the check
+// for equality with the last node it went right at until the end.
+func (lessOrder[K, V, A]) search(node *tree[K, V, A], key K) (p path, found bool) {
+	var candidate *tree[K, V, A]
+	at := 0
+	for node != nil {
+		if key.Less(node.entry.K) {
+			p.push(0)
+			node = node.children[0]
+		} else {
+			candidate, at = node, p.len
+			p.push(1)
+			node = node.children[1]
+		}
 	}
-	return &Node[K, V]{
-		entry:    entry,
-		h:        combinedDepth(left, right),
-		len:      len,
-		children: [2]*Node[K, V]{left, right},
+	if found = candidate != nil && !candidate.entry.K.Less(key); found {
+		p.cut(at)
 	}
+	return p, found
 }
 
-// Get retrieves the value for the given key.
-// It returns the value and true if the key exists, otherwise the zero value and false.
-func (node *Node[K, V]) Get(key K) (value V, ok bool) {
-	finger := node
-	for {
-		if finger == nil {
-			ok = false
-			return // using named returns so we keep the zero value for `value`
+// seek is core.seek comparing keys with Less. Going through core, which calls the
+// ordering by way of a dictionary, would take the walker to the heap, so instead
+// the wrappers call this directly, by way of from and within.
+func (lessOrder[K, V, A]) seek(w *walker[K, V, A], node *tree[K, V, A], key K, bound Bound, dir int) {
+	if bound == Unbounded {
+		w.pushSpine(node, 1-dir)
+		return
+	}
+	for node != nil {
+		// compare as if ascending: the node is ahead of key if ahead >= behind
+		ahead, behind := node.entry.K, key
+		if dir == 0 {
+			ahead, behind = behind, ahead
 		}
-		if key.Less(finger.entry.K) {
-			finger = finger.children[0]
-		} else if finger.entry.K.Less(key) {
-			finger = finger.children[1]
+		var in bool
+		if bound == Exclusive {
+			in = behind.Less(ahead)
 		} else {
-			// equal
-			return finger.entry.V, true
+			in = !ahead.Less(behind)
+		}
+		if !in {
+			// This node and its entire subtree on the near side are behind key.
+			node = node.children[dir]
+			continue
 		}
+		w.stack[w.top] = node
+		w.top++
+		node = node.children[1-dir]
 	}
 }
 
-// Insert adds a key-value pair to the map.
-// If the key already exists, its value is updated.
-// Returns a new map containing the change.
-func (node *Node[K, V]) Insert(key K, value V) *Node[K, V] {
+// from is core.from seeking with lessOrder.seek.
+func (o lessOrder[K, V, A]) from(node *tree[K, V, A], k K, dir int) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		var w walker[K, V, A]
+		o.seek(&w, node, k, Inclusive, dir)
+		for n := w.next(dir); n != nil; n = w.next(dir) {
+			if !yield(n.entry.K, n.entry.V) {
+				return
+			}
+		}
+	}
+}
+
+// within is core.within seeking with lessOrder.seek.
+func (o lessOrder[K, V, A]) within(node *tree[K, V, A], lo, hi K, opts RangeOptions, dir int) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		start, startBound, end, endBound := ends(lo, hi, opts, dir)
+		var w walker[K, V, A]
+		o.seek(&w, node, start, startBound, dir)
+		core[K, V, A, lessOrder[K, V, A]]{ord: o}.eachShort(&w, end, endBound, dir, yield)
+	}
+}
+
+// insert is core.insert recursing with Less rather than searching through the ordering.
+func (o lessOrder[K, V, A]) insert(c core[K, V, A, lessOrder[K, V, A]], node *tree[K, V, A], key K, value V, eq func(old, new V) bool) *tree[K, V, A] {
 	if node == nil {
-		return mk_OrdMap(Entry[K, V]{key, value}, nil, nil)
+		return c.mk(Entry[K, V]{key, value}, nil, nil)
 	}
 	entry, left, right := node.entry, node.children[0], node.children[1]
 	if node.entry.K.Less(key) {
-		right = right.Insert(key, value)
+		right = o.insert(c, right, key, value, eq)
+		if right == node.children[1] {
+			return node
+		}
 	} else if key.Less(node.entry.K) {
-		left = left.Insert(key, value)
+		left = o.insert(c, left, key, value, eq)
+		if left == node.children[0] {
+			return node
+		}
 	} else { // equals
+		if eq != nil && eq(entry.V, value) {
+			return node
+		}
 		entry = Entry[K, V]{key, value}
 	}
-	return rotate(entry, left, right)
+	return c.rotate(entry, left, right)
 }
 
-// Remove deletes the key from the map.
-// If the key does not exist, the map is returned unchanged.
-// Returns a new map containing the change.
-func (node *Node[K, V]) Remove(key K) *Node[K, V] {
+// remove is core.remove recursing with Less rather than searching through the ordering.
+func (o lessOrder[K, V, A]) remove(c core[K, V, A, lessOrder[K, V, A]], node *tree[K, V, A], key K) *tree[K, V, A] {
 	if node == nil {
 		return nil
 	}
 	entry, left, right := node.entry, node.children[0], node.children[1]
 	if node.entry.K.Less(key) {
-		right = right.Remove(key)
+		right = o.remove(c, right, key)
+		if right == node.children[1] {
+			return node
+		}
 	} else if key.Less(node.entry.K) {
-		left = left.Remove(key)
-	} else { // equals
-		max := left.Max()
-		if max == nil {
-			return right
-		} else {
-			left = left.Remove(max.K)
-			entry = *max
+		left = o.remove(c, left, key)
+		if left == node.children[0] {
+			return node
 		}
+	} else { // equals
+		return c.without(node)
 	}
-	return rotate(entry, left, right)
+	return c.rotate(entry, left, right)
 }
 
-func rotate[K Comparable[K], V any](entry Entry[K, V], left, right *Node[K, V]) *Node[K, V] {
-	if right.height()-left.height() > 1 { // implies right != nil
-		// single left
-		rl := right.children[0]
-		rr := right.children[1]
-		if combinedDepth(left, rl)-rr.height() > 1 {
-			// double rotation
-			return mk_OrdMap(
-				rl.entry,
-				mk_OrdMap(entry, left, rl.children[0]),
-				mk_OrdMap(right.entry, rl.children[1], rr),
-			)
-		}
-		return mk_OrdMap(right.entry, mk_OrdMap(entry, left, rl), rr)
-	}
-	if left.height()-right.height() > 1 { // implies left != nil
-		// single right
-		ll := left.children[0]
-		lr := left.children[1]
-		if combinedDepth(right, lr)-ll.height() > 1 {
-			// double rotation
-			return mk_OrdMap(
-				lr.entry,
-				mk_OrdMap(left.entry, ll, lr.children[0]),
-				mk_OrdMap(entry, lr.children[1], right),
-			)
+func nodeCore[K Comparable[K], V any]() core[K, V, struct{}, lessOrder[K, V, struct{}]] {
+	return core[K, V, struct{}, lessOrder[K, V, struct{}]]{}
+}
+
+// Get retrieves the value for the given key.
+// It returns the value and true if the key exists, otherwise the zero value and false.
+func (node *Node[K, V]) Get(key K) (value V, ok bool) {
+	// the loop of lessOrder.find, written out so that it is not behind a call
+	for finger := node.tree(); finger != nil; {
+		if key.Less(finger.entry.K) {
+			finger = finger.children[0]
+		} else if finger.entry.K.Less(key) {
+			finger = finger.children[1]
+		} else {
+			return finger.entry.V, true
 		}
-		return mk_OrdMap(left.entry, ll, mk_OrdMap(entry, lr, right))
 	}
-	return mk_OrdMap(entry, left, right)
+	return // using named returns so we keep the zero value for `value`
 }
 
-// Len returns the number of elements in the map.
-func (node *Node[K, V]) Len() int {
-	if node == nil {
-		return 0
-	}
-	return node.len
+// Insert adds a key-value pair to the map.
+// If the key already exists, its value is updated.
+// Returns a new map containing the change.
+func (node *Node[K, V]) Insert(key K, value V) *Node[K, V] {
+	return asNode(lessOrder[K, V, struct{}]{}.insert(nodeCore[K, V](), node.tree(), key, value, nil))
 }
 
-type entriesFrame[K Comparable[K], V any] struct {
-	node     *Node[K, V]
-	leftDone bool
+// InsertFunc adds a key-value pair to the map like Insert, but if the key already
+// exists and eq reports the old and new values as equal, the existing entry is kept
+// and the map is returned unchanged (the very same pointer).
+// This allows cheap change detection via pointer equality.
+func (node *Node[K, V]) InsertFunc(key K, value V, eq func(old, new V) bool) *Node[K, V] {
+	return asNode(lessOrder[K, V, struct{}]{}.insert(nodeCore[K, V](), node.tree(), key, value, eq))
 }
 
-// Entries returns a slice of all key-value pairs in the map, sorted by key.
-func (node *Node[K, V]) Entries() []Entry[K, V] {
-	elems := make([]Entry[K, V], 0, node.Len())
-	if node == nil {
-		return elems
-	}
-	var preallocated [20]entriesFrame[K, V] // preallocate on stack for common case
-	stack := preallocated[:0]
-	stack = append(stack, entriesFrame[K, V]{node, false})
-	for len(stack) > 0 {
-		top := &stack[len(stack)-1]
+// Update inserts, modifies or deletes the entry for key in a single descent.
+// fn receives the current value (or the zero value) and whether the key exists.
+// It returns the new value and whether the entry should be kept in the map.
+// If the key does not exist and fn does not keep it, the map is returned unchanged.
+// Returns a new map containing the change.
+func (node *Node[K, V]) Update(key K, fn func(old V, exists bool) (V, bool)) *Node[K, V] {
+	return asNode(nodeCore[K, V]().update(node.tree(), key, fn))
+}
 
-		if !top.leftDone {
-			if top.node.children[0] != nil {
-				stack = append(stack, entriesFrame[K, V]{top.node.children[0], false})
-			}
-			top.leftDone = true
-		} else {
-			stack = stack[:len(stack)-1] // pop
-			elems = append(elems, top.node.entry)
-			if top.node.children[1] != nil {
-				stack = append(stack, entriesFrame[K, V]{top.node.children[1], false})
-			}
-		}
-	}
-	return elems
+// Remove deletes the key from the map.
+// If the key does not exist, the map is returned unchanged (the very same pointer).
+// Returns a new map containing the change.
+func (node *Node[K, V]) Remove(key K) *Node[K, V] {
+	return asNode(lessOrder[K, V, struct{}]{}.remove(nodeCore[K, V](), node.tree(), key))
 }
 
-func (node *Node[K, V]) extreme(dir int) *Entry[K, V] {
-	if node == nil {
-		return nil
-	}
-	finger := node
-	for finger.children[dir] != nil {
-		finger = finger.children[dir]
-	}
-	return &finger.entry
+// Split partitions the map around key.
+// left contains all keys less than key and right all keys greater than key.
+// If key is present, its value is returned and found is true.
+// Runs in O(log N) and shares structure with the original map.
+func (node *Node[K, V]) Split(key K) (left *Node[K, V], value V, found bool, right *Node[K, V]) {
+	l, value, found, r := nodeCore[K, V]().split(node.tree(), key)
+	return asNode(l), value, found, asNode(r)
+}
+
+// Join concatenates two maps where all keys in left are less than all keys in right.
+// The ordering precondition is not checked.
+// Runs in O(log N) and shares structure with both inputs.
+func Join[K Comparable[K], V any](left, right *Node[K, V]) *Node[K, V] {
+	return asNode(nodeCore[K, V]().concat(left.tree(), right.tree()))
+}
+
+// Union returns a map containing all keys from a and b.
+// For keys present in both maps the value is resolve(k, va, vb); if resolve is nil,
+// the value from b wins.
+// Runs in O(m log(n/m + 1)) for sizes m <= n and shares unchanged subtrees with the inputs.
+func Union[K Comparable[K], V any](a, b *Node[K, V], resolve func(k K, va, vb V) V) *Node[K, V] {
+	return asNode(nodeCore[K, V]().union(a.tree(), b.tree(), resolve))
+}
+
+// Intersection returns a map containing only keys present in both a and b.
+// The value for each key is resolve(k, va, vb); if resolve is nil, the value from b wins.
+// Runs in O(m log(n/m + 1)) for sizes m <= n.
+func Intersection[K Comparable[K], V any](a, b *Node[K, V], resolve func(k K, va, vb V) V) *Node[K, V] {
+	return asNode(nodeCore[K, V]().intersection(a.tree(), b.tree(), resolve))
+}
+
+// Difference returns a map containing the entries of a whose keys are not present in b.
+// Runs in O(m log(n/m + 1)) for sizes m <= n and shares unchanged subtrees with a.
+func Difference[K Comparable[K], V any](a, b *Node[K, V]) *Node[K, V] {
+	return asNode(nodeCore[K, V]().difference(a.tree(), b.tree()))
+}
+
+// RemoveRange deletes all keys between lo and hi.
+// opts controls whether each end is inclusive, exclusive or unbounded.
+// Whole subtrees within the range are dropped at once, so this runs in O(log N)
+// regardless of how many entries are removed.
+// If no keys fall within the range, the map is returned unchanged.
+func (node *Node[K, V]) RemoveRange(lo, hi K, opts RangeOptions) *Node[K, V] {
+	return asNode(nodeCore[K, V]().removeRange(node.tree(), lo, hi, opts))
+}
+
+// TruncateBefore deletes all keys strictly less than key.
+// Runs in O(log N) regardless of how many entries are removed.
+func (node *Node[K, V]) TruncateBefore(key K) *Node[K, V] {
+	return asNode(nodeCore[K, V]().keepAbove(node.tree(), key, true))
+}
+
+// TruncateAfter deletes all keys strictly greater than key.
+// Runs in O(log N) regardless of how many entries are removed.
+func (node *Node[K, V]) TruncateAfter(key K) *Node[K, V] {
+	return asNode(nodeCore[K, V]().keepBelow(node.tree(), key, true))
+}
+
+// Len returns the number of elements in the map.
+func (node *Node[K, V]) Len() int {
+	return node.tree().length()
+}
+
+// Entries returns a slice of all key-value pairs in the map, sorted by key.
+func (node *Node[K, V]) Entries() []Entry[K, V] {
+	return node.tree().entries()
 }
 
 // Min returns the entry with the smallest key in the map.
 // Returns nil if the map is empty.
 func (node *Node[K, V]) Min() *Entry[K, V] {
-	return node.extreme(0)
+	return node.tree().extreme(0)
 }
 
 // Max returns the entry with the largest key in the map.
 // Returns nil if the map is empty.
 func (node *Node[K, V]) Max() *Entry[K, V] {
-	return node.extreme(1)
+	return node.tree().extreme(1)
 }
 
-// All returns an iterator over all key-value pairs in the map, sorted by key (ascending).
-func (node *Node[K, V]) All() iter.Seq2[K, V] {
-	return func(yield func(K, V) bool) {
-		var step func(*Node[K, V]) bool
-		step = func(node *Node[K, V]) bool {
-			if node == nil {
-				return true
-			}
-			if !step(node.children[0]) {
-				return false
-			}
-			if !yield(node.entry.K, node.entry.V) {
-				return false
-			}
-			if !step(node.children[1]) {
-				return false
-			}
-			return true
-		}
-		step(node)
-	}
+// Floor returns the entry with the greatest key less than or equal to key.
+// Returns nil if there is no such entry.
+func (node *Node[K, V]) Floor(key K) *Entry[K, V] {
+	return nodeCore[K, V]().neighbor(node.tree(), key, 0, true)
 }
 
-// Backward returns an iterator over all key-value pairs in the map, sorted by key (descending).
-func (node *Node[K, V]) Backward() iter.Seq2[K, V] {
-	return func(yield func(K, V) bool) {
-		var step func(*Node[K, V]) bool
-		step = func(node *Node[K, V]) bool {
-			if node == nil {
-				return true
-			}
-			if !step(node.children[1]) {
-				return false
-			}
-			if !yield(node.entry.K, node.entry.V) {
-				return false
-			}
-			if !step(node.children[0]) {
-				return false
-			}
-			return true
-		}
-		step(node)
-	}
+// Ceiling returns the entry with the least key greater than or equal to key.
+// Returns nil if there is no such entry.
+func (node *Node[K, V]) Ceiling(key K) *Entry[K, V] {
+	return nodeCore[K, V]().neighbor(node.tree(), key, 1, true)
+}
+
+// Lower returns the entry with the greatest key strictly less than key.
+// Returns nil if there is no such entry.
+func (node *Node[K, V]) Lower(key K) *Entry[K, V] {
+	return nodeCore[K, V]().neighbor(node.tree(), key, 0, false)
+}
+
+// Higher returns the entry with the least key strictly greater than key.
+// Returns nil if there is no such entry.
+func (node *Node[K, V]) Higher(key K) *Entry[K, V] {
+	return nodeCore[K, V]().neighbor(node.tree(), key, 1, false)
+}
+
+// PopMin removes the entry with the smallest key in a single descent.
+// Returns the removed entry and the new map, or nil and the unchanged map if it is empty.
+func (node *Node[K, V]) PopMin() (*Entry[K, V], *Node[K, V]) {
+	min, rest := nodeCore[K, V]().popExtreme(node.tree(), 0)
+	return min, asNode(rest)
+}
+
+// PopMax removes the entry with the largest key in a single descent.
+// Returns the removed entry and the new map, or nil and the unchanged map if it is empty.
+func (node *Node[K, V]) PopMax() (*Entry[K, V], *Node[K, V]) {
+	max, rest := nodeCore[K, V]().popExtreme(node.tree(), 1)
+	return max, asNode(rest)
+}
+
+// DeleteMin removes the entry with the smallest key.
+// Returns a new map containing the change.
+func (node *Node[K, V]) DeleteMin() *Node[K, V] {
+	_, rest := node.PopMin()
+	return rest
+}
+
+// DeleteMax removes the entry with the largest key.
+// Returns a new map containing the change.
+func (node *Node[K, V]) DeleteMax() *Node[K, V] {
+	_, rest := node.PopMax()
+	return rest
+}
+
+// At returns the entry at position i in key order (0-based).
+// Returns nil if i is out of range.
+// Runs in O(log N) by using the subtree sizes stored in each node.
+func (node *Node[K, V]) At(i int) *Entry[K, V] {
+	return node.tree().at(i)
+}
+
+// IndexOf returns the position of key in key order (0-based) and true if the key exists,
+// otherwise 0 and false.
+func (node *Node[K, V]) IndexOf(key K) (int, bool) {
+	return nodeCore[K, V]().indexOf(node.tree(), key)
+}
+
+// Rank returns the number of keys in the map that are strictly less than key.
+// The key itself does not need to be present in the map.
+func (node *Node[K, V]) Rank(key K) int {
+	return nodeCore[K, V]().rank(node.tree(), key)
+}
+
+// All returns an iterator over all key-value pairs in the map, sorted by key (ascending).
+func (node *Node[K, V]) All() iter.Seq2[K, V] {
+	return node.tree().all(1)
+}
+
+// Backward returns an iterator over all key-value pairs in the map, sorted by key (descending).
+func (node *Node[K, V]) Backward() iter.Seq2[K, V] {
+	return node.tree().all(0)
 }
 
 // From returns an iterator over key-value pairs starting from the first key >= k.
 // The iteration proceeds in ascending order.
 func (node *Node[K, V]) From(k K) iter.Seq2[K, V] {
+	return lessOrder[K, V, struct{}]{}.from(node.tree(), k, 1)
+}
+
+// BackwardFrom returns an iterator over key-value pairs starting from the first key <= k.
+// The iteration proceeds in descending order.
+func (node *Node[K, V]) BackwardFrom(k K) iter.Seq2[K, V] {
+	return lessOrder[K, V, struct{}]{}.from(node.tree(), k, 0)
+}
+
+// Range returns an iterator over key-value pairs with keys between lo and hi.
+// opts controls whether each end is inclusive, exclusive or unbounded.
+// The iteration proceeds in ascending order.
+func (n NodeBuiltin[K, V]) Range(lo, hi K, opts RangeOptions) iter.Seq2[K, V] {
 	return func(yield func(K, V) bool) {
-		// Phase 2: Unconditional Traversal
-		// Used once we are strictly inside the valid range.
-		// No comparisons against 'k' are performed here.
-		var iterate func(*Node[K, V]) bool
-		iterate = func(n *Node[K, V]) bool {
-			if n == nil {
-				return true
-			}
-			if !iterate(n.children[0]) {
-				return false
-			}
-			if !yield(n.entry.K, n.entry.V) {
-				return false
+		for b, v := range n.n.Range(Builtin[K]{lo}, Builtin[K]{hi}, opts) {
+			if !yield(b.value, v) {
+				return
 			}
-			return iterate(n.children[1])
 		}
+	}
+}
 
-		// Phase 1: Seeking the Boundary
-		// Performs comparisons to prune left subtrees.
-		var seek func(*Node[K, V]) bool
-		seek = func(n *Node[K, V]) bool {
-			if n == nil {
-				return true
+// BackwardRange returns an iterator over key-value pairs with keys between lo and hi.
+// opts controls whether each end is inclusive, exclusive or unbounded.
+// The iteration proceeds in descending order.
+func (n NodeBuiltin[K, V]) BackwardRange(lo, hi K, opts RangeOptions) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for b, v := range n.n.BackwardRange(Builtin[K]{lo}, Builtin[K]{hi}, opts) {
+			if !yield(b.value, v) {
+				return
 			}
+		}
+	}
+}
 
-			// If n < k:
-			// This node and its entire left subtree are too small.
-			// Skip them and continue seeking in the right subtree.
-			if n.entry.K.Less(k) {
-				return seek(n.children[1])
+// Keys returns an iterator over all keys in the map in ascending order.
+func (n NodeBuiltin[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for b := range n.n.Keys() {
+			if !yield(b.value) {
+				return
 			}
+		}
+	}
+}
+
+// Values returns an iterator over all values in the map in ascending order of their keys.
+func (n NodeBuiltin[K, V]) Values() iter.Seq[V] {
+	return n.n.Values()
+}
+
+// KeysSlice returns a slice of all keys in the map in ascending order.
+func (n NodeBuiltin[K, V]) KeysSlice() []K {
+	return slices.AppendSeq(make([]K, 0, n.Len()), n.Keys())
+}
+
+// ValuesSlice returns a slice of all values in the map in ascending order of their keys.
+func (n NodeBuiltin[K, V]) ValuesSlice() []V {
+	return n.n.ValuesSlice()
+}
+
+// KeysRange returns an iterator over the keys between lo and hi in ascending order.
+// See Range for details.
+func (n NodeBuiltin[K, V]) KeysRange(lo, hi K, opts RangeOptions) iter.Seq[K] {
+	return keysOf(n.Range(lo, hi, opts))
+}
+
+// ValuesRange returns an iterator over the values of keys between lo and hi
+// in ascending order of their keys.
+// See Range for details.
+func (n NodeBuiltin[K, V]) ValuesRange(lo, hi K, opts RangeOptions) iter.Seq[V] {
+	return n.n.ValuesRange(Builtin[K]{lo}, Builtin[K]{hi}, opts)
+}
+
+// Range returns an iterator over key-value pairs with keys between lo and hi.
+// opts controls whether each end is inclusive, exclusive or unbounded.
+// The iteration proceeds in ascending order.
+func (node *Node[K, V]) Range(lo, hi K, opts RangeOptions) iter.Seq2[K, V] {
+	return lessOrder[K, V, struct{}]{}.within(node.tree(), lo, hi, opts, 1)
+}
 
-			// If n >= k:
-			// 1. The boundary might be in the left subtree, so we 'seek' left.
-			if !seek(n.children[0]) {
-				return false
+// BackwardRange returns an iterator over key-value pairs with keys between lo and hi.
+// opts controls whether each end is inclusive, exclusive or unbounded.
+// The iteration proceeds in descending order.
+func (node *Node[K, V]) BackwardRange(lo, hi K, opts RangeOptions) iter.Seq2[K, V] {
+	return lessOrder[K, V, struct{}]{}.within(node.tree(), lo, hi, opts, 0)
+}
+
+// Keys returns an iterator over all keys in the map in ascending order.
+func (node *Node[K, V]) Keys() iter.Seq[K] {
+	return node.tree().keys()
+}
+
+// Values returns an iterator over all values in the map in ascending order of their keys.
+func (node *Node[K, V]) Values() iter.Seq[V] {
+	return node.tree().values()
+}
+
+// KeysSlice returns a slice of all keys in the map in ascending order.
+func (node *Node[K, V]) KeysSlice() []K {
+	return slices.AppendSeq(make([]K, 0, node.Len()), node.Keys())
+}
+
+// ValuesSlice returns a slice of all values in the map in ascending order of their keys.
+func (node *Node[K, V]) ValuesSlice() []V {
+	return slices.AppendSeq(make([]V, 0, node.Len()), node.Values())
+}
+
+// KeysRange returns an iterator over the keys between lo and hi in ascending order.
+// See Range for details.
+func (node *Node[K, V]) KeysRange(lo, hi K, opts RangeOptions) iter.Seq[K] {
+	return keysOf(node.Range(lo, hi, opts))
+}
+
+// ValuesRange returns an iterator over the values of keys between lo and hi
+// in ascending order of their keys.
+// See Range for details.
+func (node *Node[K, V]) ValuesRange(lo, hi K, opts RangeOptions) iter.Seq[V] {
+	return valuesOf(node.Range(lo, hi, opts))
+}
+
+// keysOf drops the values from seq.
+func keysOf[K, V any](seq iter.Seq2[K, V]) iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k := range seq {
+			if !yield(k) {
+				return
 			}
+		}
+	}
+}
 
-			// 2. This node is valid.
-			if !yield(n.entry.K, n.entry.V) {
-				return false
+// valuesOf drops the keys from seq.
+func valuesOf[K, V any](seq iter.Seq2[K, V]) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, v := range seq {
+			if !yield(v) {
+				return
 			}
+		}
+	}
+}
+
+// tree is a node of the AVL tree behind all the map types, which also serves as the handle
+// of the subtree rooted at it. Besides the entry it caches its height, the number of
+// entries in its subtree and an augmentation computed from the entry and the children,
+// such as the hash of the subtree for NodeMerkle. Trees without one use struct{}.
+type tree[K, V, A any] struct {
+	entry Entry[K, V]
+	aug   A // ahead of the ints so that struct{} does not add trailing padding
+	h     int32
+	// owned marks a node that a Builder has allocated and may still modify in place.
+	// Only that builder can reach it until it freezes, which clears the mark again.
+	// It fits in the padding after h, so it does not make the node any larger.
+	owned    bool
+	len      int
+	children [2]*tree[K, V, A]
+}
+
+func (node *tree[K, V, A]) height() int {
+	if node == nil {
+		return 0
+	}
+	return int(node.h)
+}
+
+func (node *tree[K, V, A]) length() int {
+	if node == nil {
+		return 0
+	}
+	return node.len
+}
+
+// ordering tells the algorithms of core how to compare keys.
+// Besides compare it implements the loops that descend the tree towards a key,
+// so that they can compare keys directly rather than through a call to compare
+// for every level. Algorithms needing more than the node found take the path
+// that search returns.
+type ordering[K, V, A any] interface {
+	// compare returns a negative number when a < b, a positive number when a > b
+	// and zero when they are equal, same as cmp.Compare.
+	compare(a, b K) int
+	// find returns the node holding key, or nil if there is none.
+	find(node *tree[K, V, A], key K) *tree[K, V, A]
+	// search returns the path from node towards key, ending at the node holding key
+	// if there is one, in which case found is true, or else at the last node visited.
+	search(node *tree[K, V, A], key K) (p path, found bool)
+}
+
+// path records the way a search took from the root: how many nodes it visited and,
+// for each of them, whether it went on to the right child. Unlike the nodes themselves
+// it is small enough to be passed around in registers; walker.follow retrieves them.
+type path struct {
+	len   int
+	right [2]uint64 // one bit per node, enough for maxHeight
+}
+
+// push records a step from the next node in direction dir.
+func (p *path) push(dir int) {
+	i := uint(p.len)
+	p.right[i/64%2] |= uint64(dir) << (i % 64)
+	p.len++
+}
 
-			// 3. OPTIMIZATION:
-			// Since n >= k, all nodes in the right subtree are > k.
-			// Switch to unconditional 'iterate' (no comparisons).
-			return iterate(n.children[1])
+// cut shortens the path to end at its i-th node.
+func (p *path) cut(i int) {
+	u := uint(i)
+	p.right[u/64%2] &^= 1 << (u % 64)
+	p.len = i + 1
+}
+
+// dir returns the direction in which the path leaves its i-th node.
+func (p *path) dir(i int) int {
+	u := uint(i)
+	return int(p.right[u/64%2] >> (u % 64) & 1)
+}
+
+// core implements the algorithms on trees ordered by O, shared by all the map types.
+// The map types are thin wrappers converting between their handles and trees.
+type core[K, V, A any, O ordering[K, V, A]] struct {
+	ord O
+	// augment computes the augmentation of a node from its entry and children.
+	// It is nil for trees without augmentation, which thus skip the call.
+	augment func(entry Entry[K, V], left, right *tree[K, V, A]) A
+}
+
+func (c core[K, V, A, O]) mk(entry Entry[K, V], left, right *tree[K, V, A]) *tree[K, V, A] {
+	node := &tree[K, V, A]{
+		entry:    entry,
+		h:        int32(max(left.height(), right.height()) + 1),
+		len:      1 + left.length() + right.length(),
+		children: [2]*tree[K, V, A]{left, right},
+	}
+	if c.augment != nil {
+		node.aug = c.augment(entry, left, right)
+	}
+	return node
+}
+
+// fromSorted builds a perfectly balanced tree from entries sorted by strictly ascending key.
+func (c core[K, V, A, O]) fromSorted(entries []Entry[K, V]) *tree[K, V, A] {
+	if len(entries) == 0 {
+		return nil
+	}
+	mid := len(entries) / 2
+	return c.mk(entries[mid], c.fromSorted(entries[:mid]), c.fromSorted(entries[mid+1:]))
+}
+
+// fromEntries builds a tree from entries in arbitrary order, sorting them in place.
+func (c core[K, V, A, O]) fromEntries(entries []Entry[K, V]) *tree[K, V, A] {
+	sorted := true
+	for i := 1; i < len(entries) && sorted; i++ {
+		sorted = c.ord.compare(entries[i-1].K, entries[i].K) < 0
+	}
+	if sorted {
+		return c.fromSorted(entries)
+	}
+	// stable so that among equal keys the last one in the input comes last
+	slices.SortStableFunc(entries, func(a, b Entry[K, V]) int {
+		return c.ord.compare(a.K, b.K)
+	})
+	deduped := entries[:0]
+	for i, e := range entries {
+		if i+1 < len(entries) && c.ord.compare(e.K, entries[i+1].K) == 0 {
+			continue // superseded by the next one
 		}
+		deduped = append(deduped, e)
+	}
+	return c.fromSorted(deduped)
+}
 
-		seek(node)
+func (c core[K, V, A, O]) get(node *tree[K, V, A], key K) (value V, ok bool) {
+	if found := c.ord.find(node, key); found != nil {
+		return found.entry.V, true
 	}
+	return // using named returns so we keep the zero value for `value`
 }
 
-// BackwardFrom returns an iterator over key-value pairs starting from the first key <= k.
-// The iteration proceeds in descending order.
-func (node *Node[K, V]) BackwardFrom(k K) iter.Seq2[K, V] {
-	return func(yield func(K, V) bool) {
-		// Phase 2: Unconditional Reverse Traversal
-		// Used for left subtrees of valid nodes (since Left < Node <= k).
-		// Traverses: Right -> Node -> Left
-		var iterate func(*Node[K, V]) bool
-		iterate = func(n *Node[K, V]) bool {
-			if n == nil {
-				return true
-			}
-			// 1. Right (Largest)
-			if !iterate(n.children[1]) {
-				return false
-			}
-			// 2. Current
-			if !yield(n.entry.K, n.entry.V) {
-				return false
-			}
-			// 3. Left (Smallest)
-			return iterate(n.children[0])
+func (c core[K, V, A, O]) insert(node *tree[K, V, A], key K, value V, eq func(old, new V) bool) *tree[K, V, A] {
+	if node == nil {
+		return c.mk(Entry[K, V]{key, value}, nil, nil)
+	}
+	entry, left, right := node.entry, node.children[0], node.children[1]
+	d := c.ord.compare(key, node.entry.K)
+	if d > 0 {
+		right = c.insert(right, key, value, eq)
+		if right == node.children[1] {
+			return node
+		}
+	} else if d < 0 {
+		left = c.insert(left, key, value, eq)
+		if left == node.children[0] {
+			return node
+		}
+	} else { // equals
+		if eq != nil && eq(entry.V, value) {
+			return node
+		}
+		entry = Entry[K, V]{key, value}
+	}
+	return c.rotate(entry, left, right)
+}
+
+func (c core[K, V, A, O]) update(node *tree[K, V, A], key K, fn func(old V, exists bool) (V, bool)) *tree[K, V, A] {
+	if node == nil {
+		var zero V
+		value, keep := fn(zero, false)
+		if !keep {
+			return nil
+		}
+		return c.mk(Entry[K, V]{key, value}, nil, nil)
+	}
+	entry, left, right := node.entry, node.children[0], node.children[1]
+	d := c.ord.compare(key, node.entry.K)
+	if d > 0 {
+		right = c.update(right, key, fn)
+		if right == node.children[1] {
+			return node
+		}
+	} else if d < 0 {
+		left = c.update(left, key, fn)
+		if left == node.children[0] {
+			return node
+		}
+	} else { // equals
+		value, keep := fn(entry.V, true)
+		if keep {
+			entry = Entry[K, V]{key, value}
+		} else if left == nil {
+			return right
+		} else {
+			left, entry = c.splitExtreme(left, 1)
+		}
+	}
+	return c.rotate(entry, left, right)
+}
+
+func (c core[K, V, A, O]) remove(node *tree[K, V, A], key K) *tree[K, V, A] {
+	if node == nil {
+		return nil
+	}
+	entry, left, right := node.entry, node.children[0], node.children[1]
+	d := c.ord.compare(key, node.entry.K)
+	if d > 0 {
+		right = c.remove(right, key)
+		if right == node.children[1] {
+			return node
+		}
+	} else if d < 0 {
+		left = c.remove(left, key)
+		if left == node.children[0] {
+			return node
+		}
+	} else { // equals
+		return c.without(node)
+	}
+	return c.rotate(entry, left, right)
+}
+
+// without returns the children of node joined together, dropping its own entry.
+func (c core[K, V, A, O]) without(node *tree[K, V, A]) *tree[K, V, A] {
+	if node.children[0] == nil {
+		return node.children[1]
+	}
+	left, entry := c.splitExtreme(node.children[0], 1)
+	return c.rotate(entry, left, node.children[1])
+}
+
+// rotate builds a node out of entry, left and right, restoring the AVL invariant
+// if their heights differ by two.
+func (c core[K, V, A, O]) rotate(entry Entry[K, V], left, right *tree[K, V, A]) *tree[K, V, A] {
+	if right.height()-left.height() > 1 { // implies right != nil
+		// single left
+		rl := right.children[0]
+		rr := right.children[1]
+		if max(left.height(), rl.height())+1-rr.height() > 1 {
+			// double rotation
+			return c.mk(
+				rl.entry,
+				c.mk(entry, left, rl.children[0]),
+				c.mk(right.entry, rl.children[1], rr),
+			)
 		}
+		return c.mk(right.entry, c.mk(entry, left, rl), rr)
+	}
+	if left.height()-right.height() > 1 { // implies left != nil
+		// single right
+		ll := left.children[0]
+		lr := left.children[1]
+		if max(right.height(), lr.height())+1-ll.height() > 1 {
+			// double rotation
+			return c.mk(
+				lr.entry,
+				c.mk(left.entry, ll, lr.children[0]),
+				c.mk(entry, lr.children[1], right),
+			)
+		}
+		return c.mk(left.entry, ll, c.mk(entry, lr, right))
+	}
+	return c.mk(entry, left, right)
+}
 
-		// Phase 1: Seeking the Boundary
-		// Performs comparisons to prune Right subtrees.
-		var seek func(*Node[K, V]) bool
-		seek = func(n *Node[K, V]) bool {
-			if n == nil {
-				return true
+// join builds a balanced tree out of left, entry and right where all keys in left
+// are less than entry.K and all keys in right are greater than entry.K.
+// The heights of left and right may differ arbitrarily: we descend along the spine
+// of the taller tree until we find a subtree of comparable height and rebalance on
+// the way back up, which takes O(|height(left) - height(right)|).
+func (c core[K, V, A, O]) join(left *tree[K, V, A], entry Entry[K, V], right *tree[K, V, A]) *tree[K, V, A] {
+	if left.height() > right.height()+1 {
+		return c.rotate(left.entry, left.children[0], c.join(left.children[1], entry, right))
+	}
+	if right.height() > left.height()+1 {
+		return c.rotate(right.entry, c.join(left, entry, right.children[0]), right.children[1])
+	}
+	return c.mk(entry, left, right)
+}
+
+// concat joins two trees where all keys in left are less than all keys in right.
+func (c core[K, V, A, O]) concat(left, right *tree[K, V, A]) *tree[K, V, A] {
+	if left == nil {
+		return right
+	}
+	if right == nil {
+		return left
+	}
+	rest, max := c.splitExtreme(left, 1)
+	return c.join(rest, max, right)
+}
+
+// splitExtreme detaches the smallest (dir 0) or largest (dir 1) entry,
+// returning the remaining tree and the entry.
+// Must not be called on an empty tree.
+func (c core[K, V, A, O]) splitExtreme(node *tree[K, V, A], dir int) (*tree[K, V, A], Entry[K, V]) {
+	if node.children[dir] == nil {
+		return node.children[1-dir], node.entry
+	}
+	children := node.children
+	rest, extreme := c.splitExtreme(children[dir], dir)
+	children[dir] = rest
+	return c.rotate(node.entry, children[0], children[1]), extreme
+}
+
+// popExtreme is splitExtreme for possibly empty trees, returning nil for the entry if it is.
+func (c core[K, V, A, O]) popExtreme(node *tree[K, V, A], dir int) (*Entry[K, V], *tree[K, V, A]) {
+	if node == nil {
+		return nil, nil
+	}
+	rest, extreme := c.splitExtreme(node, dir)
+	return &extreme, rest
+}
+
+func (c core[K, V, A, O]) split(node *tree[K, V, A], key K) (left *tree[K, V, A], value V, found bool, right *tree[K, V, A]) {
+	p, found := c.ord.search(node, key)
+	var w walker[K, V, A]
+	w.follow(node, p)
+	if found {
+		at := w.pop()
+		left, value, right = at.children[0], at.entry.V, at.children[1]
+	}
+	// every node on the path goes to one side, along with its subtree on that side
+	for w.top > 0 {
+		node := w.pop()
+		if p.dir(w.top) == 1 {
+			left = c.join(node.children[0], node.entry, left)
+		} else {
+			right = c.join(right, node.entry, node.children[1])
+		}
+	}
+	return left, value, found, right
+}
+
+func (c core[K, V, A, O]) union(a, b *tree[K, V, A], resolve func(k K, va, vb V) V) *tree[K, V, A] {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	left, vb, found, right := c.split(b, a.entry.K)
+	entry := a.entry
+	if found {
+		if resolve == nil {
+			entry.V = vb
+		} else {
+			entry.V = resolve(entry.K, entry.V, vb)
+		}
+	}
+	return c.join(
+		c.union(a.children[0], left, resolve),
+		entry,
+		c.union(a.children[1], right, resolve),
+	)
+}
+
+func (c core[K, V, A, O]) intersection(a, b *tree[K, V, A], resolve func(k K, va, vb V) V) *tree[K, V, A] {
+	if a == nil || b == nil {
+		return nil
+	}
+	left, vb, found, right := c.split(b, a.entry.K)
+	left = c.intersection(a.children[0], left, resolve)
+	right = c.intersection(a.children[1], right, resolve)
+	if !found {
+		return c.concat(left, right)
+	}
+	entry := Entry[K, V]{a.entry.K, vb}
+	if resolve != nil {
+		entry.V = resolve(entry.K, a.entry.V, vb)
+	}
+	return c.join(left, entry, right)
+}
+
+func (c core[K, V, A, O]) difference(a, b *tree[K, V, A]) *tree[K, V, A] {
+	if a == nil || b == nil {
+		return a
+	}
+	left, _, _, right := c.split(a, b.entry.K)
+	return c.concat(
+		c.difference(left, b.children[0]),
+		c.difference(right, b.children[1]),
+	)
+}
+
+func (c core[K, V, A, O]) aboveLower(k, lo K, bound Bound) bool {
+	switch bound {
+	case Unbounded:
+		return true
+	case Exclusive:
+		return c.ord.compare(k, lo) > 0
+	default:
+		return c.ord.compare(k, lo) >= 0
+	}
+}
+
+func (c core[K, V, A, O]) belowUpper(k, hi K, bound Bound) bool {
+	switch bound {
+	case Unbounded:
+		return true
+	case Exclusive:
+		return c.ord.compare(k, hi) < 0
+	default:
+		return c.ord.compare(k, hi) <= 0
+	}
+}
+
+func boundFor(inclusive bool) Bound {
+	if inclusive {
+		return Inclusive
+	}
+	return Exclusive
+}
+
+// keepBelow returns the tree of all keys less than key (or equal to key if inclusive),
+// dropping whole right subtrees on the way down.
+func (c core[K, V, A, O]) keepBelow(node *tree[K, V, A], key K, inclusive bool) *tree[K, V, A] {
+	if node == nil {
+		return nil
+	}
+	if !c.belowUpper(node.entry.K, key, boundFor(inclusive)) {
+		return c.keepBelow(node.children[0], key, inclusive)
+	}
+	right := c.keepBelow(node.children[1], key, inclusive)
+	if right == node.children[1] {
+		return node
+	}
+	return c.join(node.children[0], node.entry, right)
+}
+
+// keepAbove returns the tree of all keys greater than key (or equal to key if inclusive),
+// dropping whole left subtrees on the way down.
+func (c core[K, V, A, O]) keepAbove(node *tree[K, V, A], key K, inclusive bool) *tree[K, V, A] {
+	if node == nil {
+		return nil
+	}
+	if !c.aboveLower(node.entry.K, key, boundFor(inclusive)) {
+		return c.keepAbove(node.children[1], key, inclusive)
+	}
+	left := c.keepAbove(node.children[0], key, inclusive)
+	if left == node.children[0] {
+		return node
+	}
+	return c.join(left, node.entry, node.children[1])
+}
+
+func (c core[K, V, A, O]) removeRange(node *tree[K, V, A], lo, hi K, opts RangeOptions) *tree[K, V, A] {
+	if opts.Lo != Unbounded && opts.Hi != Unbounded {
+		d := c.ord.compare(lo, hi)
+		if d > 0 || d == 0 && (opts.Lo == Exclusive || opts.Hi == Exclusive) {
+			return node // empty range
+		}
+	}
+	var left, right *tree[K, V, A]
+	if opts.Lo != Unbounded {
+		left = c.keepBelow(node, lo, opts.Lo == Exclusive)
+	}
+	if opts.Hi != Unbounded {
+		right = c.keepAbove(node, hi, opts.Hi == Exclusive)
+	}
+	if left.length()+right.length() == node.length() {
+		return node
+	}
+	return c.concat(left, right)
+}
+
+func (node *tree[K, V, A]) extreme(dir int) *Entry[K, V] {
+	if node == nil {
+		return nil
+	}
+	finger := node
+	for finger.children[dir] != nil {
+		finger = finger.children[dir]
+	}
+	return &finger.entry
+}
+
+// neighbor finds the closest entry to key in direction dir (0 below, 1 above)
+// in a single descent, including key itself if inclusive is set.
+func (c core[K, V, A, O]) neighbor(node *tree[K, V, A], key K, dir int, inclusive bool) *Entry[K, V] {
+	var w walker[K, V, A]
+	c.seek(&w, node, key, boundFor(inclusive), dir)
+	if w.top == 0 {
+		return nil
+	}
+	return &w.stack[w.top-1].entry
+}
+
+func (node *tree[K, V, A]) at(i int) *Entry[K, V] {
+	if i < 0 || i >= node.length() {
+		return nil
+	}
+	finger := node
+	for {
+		leftLen := finger.children[0].length()
+		if i < leftLen {
+			finger = finger.children[0]
+		} else if i > leftLen {
+			i -= leftLen + 1
+			finger = finger.children[1]
+		} else {
+			return &finger.entry
+		}
+	}
+}
+
+func (c core[K, V, A, O]) indexOf(node *tree[K, V, A], key K) (int, bool) {
+	if rank, found := c.locate(node, key); found {
+		return rank, true
+	}
+	return 0, false
+}
+
+func (c core[K, V, A, O]) rank(node *tree[K, V, A], key K) int {
+	rank, _ := c.locate(node, key)
+	return rank
+}
+
+// locate returns the number of entries before key and whether key is present:
+// those of every node on the path to it that the path leaves to the right, along
+// with their left subtrees, and the left subtree of the node holding key.
+func (c core[K, V, A, O]) locate(node *tree[K, V, A], key K) (rank int, found bool) {
+	p, found := c.ord.search(node, key)
+	for i := 0; i < p.len; i++ {
+		dir := p.dir(i)
+		if dir == 1 {
+			rank += node.children[0].length() + 1
+		} else if found && i == p.len-1 {
+			rank += node.children[0].length()
+		}
+		node = node.children[dir]
+	}
+	return rank, found
+}
+
+// maxHeight bounds the height of any AVL tree: a tree of height h holds at least
+// Fib(h+2)-1 nodes, which for h = 92 already exceeds the address space.
+const maxHeight = 92
+
+// walker performs in-order traversal on an explicit fixed-size stack instead of
+// recursion. Being a plain value it lives on the goroutine stack, so iterating
+// neither allocates nor pays a function call per node.
+type walker[K, V, A any] struct {
+	stack [maxHeight]*tree[K, V, A]
+	top   int
+}
+
+// pushSpine pushes node and all of its descendants along direction dir.
+func (w *walker[K, V, A]) pushSpine(node *tree[K, V, A], dir int) {
+	for ; node != nil; node = node.children[dir] {
+		w.stack[w.top] = node
+		w.top++
+	}
+}
+
+// seek pushes only the nodes on the path to key that a traversal in direction dir
+// (1 ascending, 0 descending) starting at key would visit: those >= key for dir 1 and
+// <= key for dir 0, excluding key itself if bound is Exclusive and including the
+// whole spine if it is Unbounded. Subsequent calls to next(dir) start at the first such node.
+func (c core[K, V, A, O]) seek(w *walker[K, V, A], node *tree[K, V, A], key K, bound Bound, dir int) {
+	if bound == Unbounded {
+		w.pushSpine(node, 1-dir)
+		return
+	}
+	p, found := c.ord.search(node, key)
+	for i := 0; i < p.len; i++ {
+		if found && i == p.len-1 {
+			if bound == Inclusive {
+				w.stack[w.top] = node
+				w.top++
+			} else { // the traversal starts right after key
+				w.pushSpine(node.children[dir], 1-dir)
 			}
+			return
+		}
+		// Keep the nodes where the path turns towards 1-dir: they and their subtrees
+		// on the far side lie ahead of key. The rest are behind it.
+		if p.dir(i) != dir {
+			w.stack[w.top] = node
+			w.top++
+		}
+		node = node.children[p.dir(i)]
+	}
+}
+
+// follow pushes the nodes along p, starting at node.
+func (w *walker[K, V, A]) follow(node *tree[K, V, A], p path) {
+	for i := 0; i < p.len; i++ {
+		w.stack[w.top] = node
+		w.top++
+		node = node.children[p.dir(i)]
+	}
+}
+
+// pop removes the node on top of the stack, which must not be empty, and returns it.
+func (w *walker[K, V, A]) pop() *tree[K, V, A] {
+	w.top--
+	return w.stack[w.top]
+}
 
-			// Check if Node > k.
-			// (Assuming standard Less interface: k < n implies n > k)
-			if k.Less(n.entry.K) {
-				// This node is too big. The right subtree is even bigger.
-				// Skip everything here and search the left subtree.
-				return seek(n.children[0])
+// next pops the next node when travelling in direction dir (1 ascending, 0 descending).
+// Returns nil when the traversal is done.
+func (w *walker[K, V, A]) next(dir int) *tree[K, V, A] {
+	if w.top == 0 {
+		return nil
+	}
+	w.top--
+	node := w.stack[w.top]
+	w.pushSpine(node.children[dir], 1-dir)
+	return node
+}
+
+func (node *tree[K, V, A]) entries() []Entry[K, V] {
+	elems := make([]Entry[K, V], 0, node.length())
+	var w walker[K, V, A]
+	w.pushSpine(node, 0)
+	for n := w.next(1); n != nil; n = w.next(1) {
+		elems = append(elems, n.entry)
+	}
+	return elems
+}
+
+// all iterates over the whole tree in direction dir (1 ascending, 0 descending).
+func (node *tree[K, V, A]) all(dir int) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		var w walker[K, V, A]
+		w.pushSpine(node, 1-dir)
+		for n := w.next(dir); n != nil; n = w.next(dir) {
+			if !yield(n.entry.K, n.entry.V) {
+				return
 			}
+		}
+	}
+}
 
-			// If we are here, n <= k.
+func (node *tree[K, V, A]) keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		var w walker[K, V, A]
+		w.pushSpine(node, 0)
+		for n := w.next(1); n != nil; n = w.next(1) {
+			if !yield(n.entry.K) {
+				return
+			}
+		}
+	}
+}
 
-			// 1. The boundary might be deep in the Right subtree.
-			//    (There could be nodes > n but still <= k)
-			if !seek(n.children[1]) {
-				return false
+func (node *tree[K, V, A]) values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		var w walker[K, V, A]
+		w.pushSpine(node, 0)
+		for n := w.next(1); n != nil; n = w.next(1) {
+			if !yield(n.entry.V) {
+				return
 			}
+		}
+	}
+}
 
-			// 2. This node is valid.
+// from iterates in direction dir (1 ascending, 0 descending) starting at the first
+// key at or past k.
+func (c core[K, V, A, O]) from(node *tree[K, V, A], k K, dir int) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		// Comparisons against k only happen while seeking; once the stack holds
+		// the boundary, everything that follows is known to be in range.
+		var w walker[K, V, A]
+		c.seek(&w, node, k, Inclusive, dir)
+		for n := w.next(dir); n != nil; n = w.next(dir) {
 			if !yield(n.entry.K, n.entry.V) {
-				return false
+				return
 			}
+		}
+	}
+}
+
+// within iterates over the keys between lo and hi in direction dir (1 ascending, 0 descending).
+func (c core[K, V, A, O]) within(node *tree[K, V, A], lo, hi K, opts RangeOptions, dir int) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		start, startBound, end, endBound := ends(lo, hi, opts, dir)
+		var w walker[K, V, A]
+		c.seek(&w, node, start, startBound, dir)
+		c.eachShort(&w, end, endBound, dir, yield)
+	}
+}
+
+// ends orders the ends of a range by a traversal in direction dir.
+func ends[K any](lo, hi K, opts RangeOptions, dir int) (start K, startBound Bound, end K, endBound Bound) {
+	if dir == 0 {
+		return hi, opts.Hi, lo, opts.Lo
+	}
+	return lo, opts.Lo, hi, opts.Hi
+}
 
-			// 3. OPTIMIZATION:
-			// Since n <= k, all nodes in the Left subtree are < n.
-			// Therefore, they are all < k.
-			// Switch to unconditional 'iterate'.
-			return iterate(n.children[0])
+// eachShort yields the entries of the nodes that next returns for the traversal
+// on w in direction dir, but stops at end.
+func (c core[K, V, A, O]) eachShort(w *walker[K, V, A], end K, bound Bound, dir int, yield func(K, V) bool) {
+	// Only nodes short of the end are ever kept on the stack. A node comes before
+	// the one below it on the stack, so only nodes pushed onto an empty stack need
+	// to be compared against the end, which happens O(log N) times in total.
+	past := 0
+	for past < w.top && !c.short(w.stack[past].entry.K, end, bound, dir) {
+		past++ // the nodes past the end are at the bottom
+	}
+	w.top = copy(w.stack[:], w.stack[past:w.top])
+	for w.top > 0 {
+		w.top--
+		n := w.stack[w.top]
+		if !yield(n.entry.K, n.entry.V) {
+			return
 		}
+		if w.top > 0 {
+			w.pushSpine(n.children[dir], 1-dir)
+		} else {
+			c.pushShort(w, n.children[dir], end, bound, dir)
+		}
+	}
+}
 
-		seek(node)
+// short reports whether k comes before the end of a traversal in direction dir.
+func (c core[K, V, A, O]) short(k, end K, bound Bound, dir int) bool {
+	if dir == 1 {
+		return c.belowUpper(k, end, bound)
+	}
+	return c.aboveLower(k, end, bound)
+}
+
+// pushShort is pushSpine for a traversal in direction dir that stops at end.
+// Nodes past the end are skipped along with their subtrees on the far side.
+func (c core[K, V, A, O]) pushShort(w *walker[K, V, A], node *tree[K, V, A], end K, bound Bound, dir int) {
+	for ; node != nil && w.top == 0; node = node.children[1-dir] {
+		if c.short(node.entry.K, end, bound, dir) {
+			w.stack[w.top] = node
+			w.top++
+		}
 	}
+	w.pushSpine(node, 1-dir)
 }
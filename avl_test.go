@@ -2,23 +2,22 @@ package ordmap
 
 import (
 	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
 	"math/rand"
+	"reflect"
+	"slices"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 )
 
-func eq[K Comparable[K]](k1, k2 K) bool {
-	if k1.Less(k2) {
-		return false
-	}
-	if k2.Less(k1) {
-		return false
-	}
-	return true
-}
-
 func reprTree[K Comparable[K], V any](n *Node[K, V]) string {
 	if n == nil {
 		return "_"
@@ -26,40 +25,39 @@ func reprTree[K Comparable[K], V any](n *Node[K, V]) string {
 	if n.children[0] == nil && n.children[1] == nil {
 		return fmt.Sprintf("(%v)", n.entry.K)
 	}
-	return fmt.Sprintf("[%v %v %v]", reprTree(n.children[0]), n.entry.K, reprTree(n.children[1]))
+	return fmt.Sprintf("[%v %v %v]", reprTree(n.child(0)), n.entry.K, reprTree(n.child(1)))
 }
 
-func validateHeight[K Comparable[K], V any](t *testing.T, tree *Node[K, V]) {
-	if tree == nil {
-		return // empty is balanced
+// validateTree checks the AVL balance and the cached height, length and augmentation
+// of every node, and that the keys are strictly ascending in the ordering of c.
+func validateTree[K, V, A any, O ordering[K, V, A]](t *testing.T, c core[K, V, A, O], root *tree[K, V, A]) {
+	var check func(n *tree[K, V, A]) int
+	check = func(n *tree[K, V, A]) int {
+		if n == nil {
+			return 0 // empty is balanced
+		}
+		left, right := check(n.children[0]), check(n.children[1])
+		require.Contains(t, []int{-1, 0, 1}, right-left)
+		require.Equal(t, max(left, right)+1, n.height())
+		require.Equal(t, 1+n.children[0].length()+n.children[1].length(), n.len)
+		if c.augment != nil {
+			require.Equal(t, c.augment(n.entry, n.children[0], n.children[1]), n.aug)
+		}
+		return n.height()
+	}
+	check(root)
+	entries := root.entries()
+	for i := 1; i < len(entries); i++ {
+		require.Negative(t, c.ord.compare(entries[i-1].K, entries[i].K))
 	}
-	left := tree.children[0].height()
-	right := tree.children[1].height()
-	require.Contains(t, []int{-1, 0, 1}, right-left)
-	require.Equal(t, combinedDepth(tree.children[0], tree.children[1]), tree.h)
-	validateHeight(t, tree.children[0])
-	validateHeight(t, tree.children[1])
+}
+
+func validateHeight[K Comparable[K], V any](t *testing.T, tree *Node[K, V]) {
+	validateTree(t, nodeCore[K, V](), tree.tree())
 }
 
 func validateOrdered[K Comparable[K], V any](t *testing.T, root *Node[K, V]) {
-	var step func(tree *Node[K, V])
-	step = func(tree *Node[K, V]) {
-		if tree == nil {
-			return
-		}
-		key := tree.entry.K
-		if tree.children[0] != nil {
-			leftKey := tree.children[0].entry.K
-			require.True(t, leftKey.Less(key) || eq(key, leftKey))
-			step(tree.children[0])
-		}
-		if tree.children[1] != nil {
-			rightKey := tree.children[1].entry.K
-			require.True(t, key.Less(rightKey) || eq(key, rightKey))
-			step(tree.children[1])
-		}
-	}
-	step(root)
+	validateTree(t, nodeCore[K, V](), root.tree())
 }
 
 type TreeModel struct {
@@ -229,6 +227,43 @@ func TestRemoveMissing(t *testing.T) {
 	require.Equal(t, 2, tree.Len())
 }
 
+func TestNoopPreservesPointer(t *testing.T) {
+	tree, model := randomTree(200, 1000)
+	intEq := func(a, b int) bool {
+		return a == b
+	}
+	for k := -10; k < 1010; k++ {
+		if v, ok := model[k]; ok {
+			require.Same(t, tree, tree.InsertFunc(Builtin[int]{k}, v, intEq))
+			changed := tree.InsertFunc(Builtin[int]{k}, v+1, intEq)
+			require.NotSame(t, tree, changed)
+			value, _ := changed.Get(Builtin[int]{k})
+			require.Equal(t, v+1, value)
+		} else {
+			require.Same(t, tree, tree.Remove(Builtin[int]{k}))
+			require.Equal(t, tree.Len()+1, tree.InsertFunc(Builtin[int]{k}, 0, intEq).Len())
+		}
+	}
+
+	t.Run("subtrees", func(t *testing.T) {
+		tree := New[Builtin[int], int]()
+		for i := range 100 {
+			tree = tree.Insert(Builtin[int]{i * 2}, i)
+		}
+		// removing a key that falls into the right subtree leaves the left one intact
+		key := tree.entry.K.value + 2
+		tree2 := tree.Remove(Builtin[int]{key})
+		require.Same(t, tree.children[0], tree2.children[0])
+	})
+
+	t.Run("builtin", func(t *testing.T) {
+		tree := NewBuiltin[string, int]().Insert("a", 1)
+		require.Equal(t, tree, tree.InsertFunc("a", 1, intEq))
+		require.Equal(t, tree, tree.Remove("b"))
+		require.NotEqual(t, tree, tree.InsertFunc("a", 2, intEq))
+	})
+}
+
 func TestIteratorEmpty(t *testing.T) {
 	var tree *Node[Builtin[int], string]
 	count := 0
@@ -475,6 +510,797 @@ func TestBackwardFrom(t *testing.T) {
 	})
 }
 
+func TestOrderStatistics(t *testing.T) {
+	var tree *Node[Builtin[int], int]
+	require.Nil(t, tree.At(0))
+	require.Equal(t, 0, tree.Rank(Builtin[int]{5}))
+	_, ok := tree.IndexOf(Builtin[int]{5})
+	require.False(t, ok)
+
+	N := 100
+	for i := range N {
+		tree = tree.Insert(Builtin[int]{i * 2}, i) // even keys only
+	}
+
+	require.Nil(t, tree.At(-1))
+	require.Nil(t, tree.At(N))
+	for i := range N {
+		require.Equal(t, &Entry[Builtin[int], int]{Builtin[int]{i * 2}, i}, tree.At(i))
+
+		index, ok := tree.IndexOf(Builtin[int]{i * 2})
+		require.True(t, ok)
+		require.Equal(t, i, index)
+		index, ok = tree.IndexOf(Builtin[int]{i*2 + 1})
+		require.False(t, ok)
+		require.Equal(t, 0, index)
+
+		require.Equal(t, i, tree.Rank(Builtin[int]{i * 2}))
+		require.Equal(t, i+1, tree.Rank(Builtin[int]{i*2 + 1}))
+	}
+	require.Equal(t, 0, tree.Rank(Builtin[int]{-1}))
+}
+
+func TestOrderStatisticsBuiltin(t *testing.T) {
+	tree := NewBuiltin[int, string]()
+	require.Nil(t, tree.At(0))
+	tree = tree.Insert(3, "c")
+	tree = tree.Insert(1, "a")
+	tree = tree.Insert(2, "b")
+
+	require.Equal(t, &Entry[int, string]{2, "b"}, tree.At(1))
+	index, ok := tree.IndexOf(3)
+	require.True(t, ok)
+	require.Equal(t, 2, index)
+	require.Equal(t, 1, tree.Rank(2))
+}
+
+func TestSplit(t *testing.T) {
+	var empty *Node[Builtin[int], int]
+	left, value, found, right := empty.Split(Builtin[int]{0})
+	require.Nil(t, left)
+	require.Equal(t, 0, value)
+	require.False(t, found)
+	require.Nil(t, right)
+
+	var tree *Node[Builtin[int], int]
+	N := 200
+	for _, i := range rand.Perm(N) {
+		tree = tree.Insert(Builtin[int]{i * 2}, i)
+	}
+	entries := tree.Entries()
+
+	for key := -1; key <= N*2; key++ {
+		left, value, found, right := tree.Split(Builtin[int]{key})
+		validateHeight(t, left)
+		validateOrdered(t, left)
+		validateHeight(t, right)
+		validateOrdered(t, right)
+
+		pivot := (key + 1) / 2 // number of even keys < key
+		if pivot > N {
+			pivot = N
+		}
+		require.Equal(t, entries[:pivot], left.Entries())
+		if key >= 0 && key < N*2 && key%2 == 0 {
+			require.True(t, found)
+			require.Equal(t, key/2, value)
+			require.Equal(t, entries[pivot+1:], right.Entries())
+		} else {
+			require.False(t, found)
+			require.Equal(t, entries[pivot:], right.Entries())
+		}
+	}
+	require.Equal(t, N, tree.Len()) // persistence
+}
+
+func TestJoin(t *testing.T) {
+	build := func(from, to int) *Node[Builtin[int], int] {
+		var tree *Node[Builtin[int], int]
+		for i := from; i < to; i++ {
+			tree = tree.Insert(Builtin[int]{i}, i)
+		}
+		return tree
+	}
+
+	for _, sizes := range [][2]int{{0, 0}, {0, 5}, {5, 0}, {1, 1}, {1, 100}, {100, 1}, {3, 1000}, {1000, 3}, {77, 91}} {
+		t.Run(fmt.Sprintf("%v", sizes), func(t *testing.T) {
+			left := build(0, sizes[0])
+			right := build(sizes[0], sizes[0]+sizes[1])
+			joined := Join(left, right)
+			validateHeight(t, joined)
+			validateOrdered(t, joined)
+			require.Equal(t, build(0, sizes[0]+sizes[1]).Entries(), joined.Entries())
+			require.Equal(t, sizes[0]+sizes[1], joined.Len())
+			require.Equal(t, sizes[0], left.Len())
+			require.Equal(t, sizes[1], right.Len())
+		})
+	}
+
+	t.Run("split roundtrip", func(t *testing.T) {
+		tree := build(0, 500)
+		for key := 0; key < 500; key += 7 {
+			left, value, _, right := tree.Split(Builtin[int]{key})
+			joined := Join(left, Join(New[Builtin[int], int]().Insert(Builtin[int]{key}, value), right))
+			validateHeight(t, joined)
+			require.Equal(t, tree.Entries(), joined.Entries())
+		}
+	})
+}
+
+func TestSplitJoinBuiltin(t *testing.T) {
+	tree := NewBuiltin[int, string]()
+	for i := range 10 {
+		tree = tree.Insert(i, fmt.Sprint(i))
+	}
+	left, value, found, right := tree.Split(4)
+	require.True(t, found)
+	require.Equal(t, "4", value)
+	require.Equal(t, 4, left.Len())
+	require.Equal(t, 5, right.Len())
+	joined := JoinBuiltin(left, right)
+	require.Equal(t, 9, joined.Len())
+	_, ok := joined.Get(4)
+	require.False(t, ok)
+}
+
+func randomTree(n, keyRange int) (*Node[Builtin[int], int], map[int]int) {
+	var tree *Node[Builtin[int], int]
+	model := make(map[int]int)
+	for range n {
+		k, v := rand.Intn(keyRange), rand.Int()
+		tree = tree.Insert(Builtin[int]{k}, v)
+		model[k] = v
+	}
+	return tree, model
+}
+
+func modelEntries(model map[int]int) []Entry[Builtin[int], int] {
+	entries := make([]Entry[Builtin[int], int], 0, len(model))
+	for k, v := range model {
+		entries = append(entries, Entry[Builtin[int], int]{Builtin[int]{k}, v})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].K.Less(entries[j].K)
+	})
+	return entries
+}
+
+func TestSetOperations(t *testing.T) {
+	sum := func(k Builtin[int], va, vb int) int {
+		return va + vb
+	}
+	for _, sizes := range [][2]int{{0, 0}, {0, 10}, {10, 0}, {1, 1}, {100, 100}, {5, 300}, {300, 5}} {
+		t.Run(fmt.Sprintf("%v", sizes), func(t *testing.T) {
+			a, modelA := randomTree(sizes[0], 200)
+			b, modelB := randomTree(sizes[1], 200)
+
+			union := make(map[int]int)
+			unionBWins := make(map[int]int)
+			intersection := make(map[int]int)
+			difference := make(map[int]int)
+			for k, va := range modelA {
+				union[k] = va
+				unionBWins[k] = va
+				if vb, ok := modelB[k]; ok {
+					intersection[k] = va + vb
+				} else {
+					difference[k] = va
+				}
+			}
+			for k, vb := range modelB {
+				unionBWins[k] = vb
+				if va, ok := modelA[k]; ok {
+					union[k] = va + vb
+				} else {
+					union[k] = vb
+				}
+			}
+
+			check := func(expected map[int]int, tree *Node[Builtin[int], int]) {
+				validateHeight(t, tree)
+				validateOrdered(t, tree)
+				require.Equal(t, modelEntries(expected), tree.Entries())
+				require.Equal(t, len(expected), tree.Len())
+			}
+			check(union, Union(a, b, sum))
+			check(unionBWins, Union(a, b, nil))
+			check(intersection, Intersection(a, b, sum))
+			check(difference, Difference(a, b))
+
+			// inputs are left intact
+			check(modelA, a)
+			check(modelB, b)
+		})
+	}
+
+	t.Run("intersection b wins", func(t *testing.T) {
+		a := New[Builtin[int], int]().Insert(Builtin[int]{1}, 1).Insert(Builtin[int]{2}, 2)
+		b := New[Builtin[int], int]().Insert(Builtin[int]{2}, 20).Insert(Builtin[int]{3}, 30)
+		require.Equal(t, []Entry[Builtin[int], int]{{Builtin[int]{2}, 20}}, Intersection(a, b, nil).Entries())
+	})
+
+	t.Run("sharing", func(t *testing.T) {
+		a, _ := randomTree(100, 1000)
+		require.Same(t, a, Union(a, nil, sum))
+		require.Same(t, a, Union(nil, a, sum))
+		require.Same(t, a, Difference(a, nil))
+	})
+}
+
+func TestSetOperationsBuiltin(t *testing.T) {
+	defaults := NewBuiltin[string, int]().Insert("a", 1).Insert("b", 2)
+	overrides := NewBuiltin[string, int]().Insert("b", 20).Insert("c", 30)
+
+	merged := UnionBuiltin(defaults, overrides, nil)
+	require.Equal(t, []Entry[string, int]{{"a", 1}, {"b", 20}, {"c", 30}}, merged.Entries())
+
+	merged = UnionBuiltin(defaults, overrides, func(k string, va, vb int) int {
+		return va * vb
+	})
+	require.Equal(t, []Entry[string, int]{{"a", 1}, {"b", 40}, {"c", 30}}, merged.Entries())
+
+	common := IntersectionBuiltin(defaults, overrides, func(k string, va, vb int) int {
+		return va
+	})
+	require.Equal(t, []Entry[string, int]{{"b", 2}}, common.Entries())
+
+	onlyDefaults := DifferenceBuiltin(defaults, overrides)
+	require.Equal(t, []Entry[string, int]{{"a", 1}}, onlyDefaults.Entries())
+}
+
+func TestRange(t *testing.T) {
+	var tree *Node[Builtin[int], int]
+	N := 50
+	for _, i := range rand.Perm(N) {
+		tree = tree.Insert(Builtin[int]{i * 2}, i*2) // even keys only
+	}
+
+	bounds := []Bound{Inclusive, Exclusive, Unbounded}
+	for _, loBound := range bounds {
+		for _, hiBound := range bounds {
+			opts := RangeOptions{Lo: loBound, Hi: hiBound}
+			for lo := -1; lo <= N*2; lo += 3 {
+				for hi := lo - 2; hi <= N*2+1; hi += 5 {
+					expected := []int{}
+					for _, e := range tree.Entries() {
+						k := e.K.value
+						if (loBound == Inclusive && k < lo) || (loBound == Exclusive && k <= lo) {
+							continue
+						}
+						if (hiBound == Inclusive && k > hi) || (hiBound == Exclusive && k >= hi) {
+							continue
+						}
+						expected = append(expected, k)
+					}
+
+					forward := []int{}
+					for k := range tree.Range(Builtin[int]{lo}, Builtin[int]{hi}, opts) {
+						forward = append(forward, k.value)
+					}
+					require.Equal(t, expected, forward, "%v [%v, %v]", opts, lo, hi)
+
+					backward := []int{}
+					for k := range tree.BackwardRange(Builtin[int]{lo}, Builtin[int]{hi}, opts) {
+						backward = append(backward, k.value)
+					}
+					slices.Reverse(backward)
+					require.Equal(t, expected, backward, "%v [%v, %v]", opts, lo, hi)
+				}
+			}
+		}
+	}
+
+	t.Run("early termination", func(t *testing.T) {
+		count := 0
+		for range tree.Range(Builtin[int]{10}, Builtin[int]{90}, RangeOptions{}) {
+			count++
+			if count >= 3 {
+				break
+			}
+		}
+		require.Equal(t, 3, count)
+		count = 0
+		for range tree.BackwardRange(Builtin[int]{10}, Builtin[int]{90}, RangeOptions{}) {
+			count++
+			if count >= 3 {
+				break
+			}
+		}
+		require.Equal(t, 3, count)
+	})
+
+	t.Run("compares only at the ends", func(t *testing.T) {
+		compares := 0
+		entries := make([]Entry[int, int], 1000)
+		for i := range entries {
+			entries[i] = Entry[int, int]{i, i}
+		}
+		m := FromSortedFunc(func(a, b int) int {
+			compares++
+			return a - b
+		}, entries)
+		for _, opts := range []RangeOptions{{}, {Lo: Exclusive, Hi: Exclusive}} {
+			compares = 0
+			count := 0
+			for range m.Range(100, 900, opts) {
+				count++
+			}
+			require.Greater(t, count, 790)
+			require.Less(t, compares, 4*m.root.height())
+			compares = 0
+			for range m.BackwardRange(100, 900, opts) {
+			}
+			require.Less(t, compares, 4*m.root.height())
+		}
+	})
+}
+
+func TestRangeBuiltin(t *testing.T) {
+	tree := NewBuiltin[int, int]()
+	for i := range 10 {
+		tree = tree.Insert(i, i)
+	}
+	keys := []int{}
+	for k := range tree.Range(3, 6, RangeOptions{Hi: Exclusive}) {
+		keys = append(keys, k)
+	}
+	require.Equal(t, []int{3, 4, 5}, keys)
+
+	keys = []int{}
+	for k := range tree.BackwardRange(0, 6, RangeOptions{Lo: Unbounded, Hi: Inclusive}) {
+		keys = append(keys, k)
+		if k == 2 {
+			break
+		}
+	}
+	require.Equal(t, []int{6, 5, 4, 3, 2}, keys)
+
+	count := 0
+	for range tree.Range(0, 9, RangeOptions{}) {
+		count++
+		break
+	}
+	require.Equal(t, 1, count)
+}
+
+func TestNeighbors(t *testing.T) {
+	var tree *Node[Builtin[int], int]
+	require.Nil(t, tree.Floor(Builtin[int]{0}))
+	require.Nil(t, tree.Ceiling(Builtin[int]{0}))
+	require.Nil(t, tree.Lower(Builtin[int]{0}))
+	require.Nil(t, tree.Higher(Builtin[int]{0}))
+
+	N := 100
+	for _, i := range rand.Perm(N) {
+		tree = tree.Insert(Builtin[int]{i * 2}, i*2) // even keys only
+	}
+
+	key := func(e *Entry[Builtin[int], int]) int {
+		if e == nil {
+			return -100
+		}
+		return e.K.value
+	}
+	for k := -1; k <= N*2; k++ {
+		floor, ceiling, lower, higher := -100, -100, -100, -100
+		for i := range N {
+			if i*2 <= k {
+				floor = i * 2
+			}
+			if i*2 < k {
+				lower = i * 2
+			}
+			if i*2 >= k && ceiling == -100 {
+				ceiling = i * 2
+			}
+			if i*2 > k && higher == -100 {
+				higher = i * 2
+			}
+		}
+		require.Equal(t, floor, key(tree.Floor(Builtin[int]{k})), "floor %v", k)
+		require.Equal(t, ceiling, key(tree.Ceiling(Builtin[int]{k})), "ceiling %v", k)
+		require.Equal(t, lower, key(tree.Lower(Builtin[int]{k})), "lower %v", k)
+		require.Equal(t, higher, key(tree.Higher(Builtin[int]{k})), "higher %v", k)
+	}
+}
+
+func TestNeighborsBuiltin(t *testing.T) {
+	tree := NewBuiltin[int, string]()
+	require.Nil(t, tree.Floor(1))
+	tree = tree.Insert(1, "a").Insert(3, "c").Insert(5, "e")
+
+	require.Equal(t, &Entry[int, string]{3, "c"}, tree.Floor(4))
+	require.Equal(t, &Entry[int, string]{3, "c"}, tree.Floor(3))
+	require.Equal(t, &Entry[int, string]{5, "e"}, tree.Ceiling(4))
+	require.Equal(t, &Entry[int, string]{1, "a"}, tree.Lower(3))
+	require.Equal(t, &Entry[int, string]{5, "e"}, tree.Higher(3))
+	require.Nil(t, tree.Higher(5))
+}
+
+func TestUpdate(t *testing.T) {
+	increment := func(old int, exists bool) (int, bool) {
+		return old + 1, true
+	}
+	dropEven := func(old int, exists bool) (int, bool) {
+		return old, old%2 != 0
+	}
+
+	var tree *Node[Builtin[int], int]
+	model := make(map[int]int)
+	N := 200
+	for range N * 3 {
+		k := rand.Intn(N)
+		if rand.Float64() < 0.7 {
+			tree = tree.Update(Builtin[int]{k}, increment)
+			model[k] += 1
+		} else {
+			tree = tree.Update(Builtin[int]{k}, dropEven)
+			if model[k]%2 == 0 {
+				delete(model, k)
+			}
+		}
+		validateHeight(t, tree)
+		validateOrdered(t, tree)
+		require.Equal(t, modelEntries(model), tree.Entries())
+		require.Equal(t, len(model), tree.Len())
+	}
+
+	t.Run("exists", func(t *testing.T) {
+		tree := New[Builtin[int], int]().Insert(Builtin[int]{1}, 10)
+		var seen []int
+		var seenExists []bool
+		observe := func(old int, exists bool) (int, bool) {
+			seen = append(seen, old)
+			seenExists = append(seenExists, exists)
+			return old, true
+		}
+		tree.Update(Builtin[int]{1}, observe)
+		tree.Update(Builtin[int]{2}, observe)
+		require.Equal(t, []int{10, 0}, seen)
+		require.Equal(t, []bool{true, false}, seenExists)
+	})
+
+	t.Run("noop delete keeps pointer", func(t *testing.T) {
+		tree, _ := randomTree(100, 1000)
+		remove := func(old int, exists bool) (int, bool) {
+			return old, false
+		}
+		for k := 1000; k < 1010; k++ {
+			require.Same(t, tree, tree.Update(Builtin[int]{k}, remove))
+		}
+		var empty *Node[Builtin[int], int]
+		require.Nil(t, empty.Update(Builtin[int]{1}, remove))
+	})
+}
+
+func TestUpdateBuiltin(t *testing.T) {
+	counts := NewBuiltin[string, int]()
+	for _, word := range []string{"a", "b", "a", "c", "a", "b"} {
+		counts = counts.Update(word, func(old int, exists bool) (int, bool) {
+			return old + 1, true
+		})
+	}
+	require.Equal(t, []Entry[string, int]{{"a", 3}, {"b", 2}, {"c", 1}}, counts.Entries())
+
+	counts = counts.Update("b", func(old int, exists bool) (int, bool) {
+		return 0, false
+	})
+	require.Equal(t, []Entry[string, int]{{"a", 3}, {"c", 1}}, counts.Entries())
+}
+
+func TestFromSorted(t *testing.T) {
+	require.Nil(t, FromSorted[Builtin[int], int](nil))
+
+	for _, N := range []int{1, 2, 3, 7, 8, 100, 1000} {
+		entries := make([]Entry[Builtin[int], int], N)
+		for i := range N {
+			entries[i] = Entry[Builtin[int], int]{Builtin[int]{i}, i}
+		}
+		tree := FromSorted(entries)
+		validateHeight(t, tree)
+		validateOrdered(t, tree)
+		require.Equal(t, entries, tree.Entries())
+		require.Equal(t, N, tree.Len())
+
+		fromSeq := FromSortedSeq(tree.All())
+		validateHeight(t, fromSeq)
+		require.Equal(t, entries, fromSeq.Entries())
+	}
+}
+
+func TestFromSeq(t *testing.T) {
+	require.Nil(t, FromSeq(New[Builtin[int], int]().All()))
+
+	var keys []int
+	model := make(map[int]int)
+	for i := range 500 {
+		k := rand.Intn(200)
+		keys = append(keys, k)
+		model[k] = i
+	}
+	tree := FromSeq(func(yield func(Builtin[int], int) bool) {
+		for i, k := range keys {
+			if !yield(Builtin[int]{k}, i) {
+				return
+			}
+		}
+	})
+	validateHeight(t, tree)
+	validateOrdered(t, tree)
+	require.Equal(t, modelEntries(model), tree.Entries())
+}
+
+func TestFromSortedBuiltin(t *testing.T) {
+	entries := []Entry[string, int]{{"a", 1}, {"b", 2}, {"c", 3}}
+	tree := FromSortedBuiltin(entries)
+	require.Equal(t, entries, tree.Entries())
+	require.Equal(t, entries, FromSortedSeqBuiltin(tree.All()).Entries())
+
+	unsorted := func(yield func(string, int) bool) {
+		_ = yield("c", 0) && yield("a", 1) && yield("c", 3) && yield("b", 2)
+	}
+	require.Equal(t, entries, FromSeqBuiltin(unsorted).Entries())
+}
+
+func TestPop(t *testing.T) {
+	var empty *Node[Builtin[int], int]
+	min, rest := empty.PopMin()
+	require.Nil(t, min)
+	require.Nil(t, rest)
+	max, rest := empty.PopMax()
+	require.Nil(t, max)
+	require.Nil(t, rest)
+	require.Nil(t, empty.DeleteMin())
+	require.Nil(t, empty.DeleteMax())
+
+	tree, model := randomTree(200, 1000)
+	entries := modelEntries(model)
+
+	queue := tree
+	for _, expected := range entries {
+		var e *Entry[Builtin[int], int]
+		e, queue = queue.PopMin()
+		require.Equal(t, expected, *e)
+		validateHeight(t, queue)
+		validateOrdered(t, queue)
+	}
+	require.Nil(t, queue)
+
+	queue = tree
+	for i := len(entries) - 1; i >= 0; i-- {
+		var e *Entry[Builtin[int], int]
+		e, queue = queue.PopMax()
+		require.Equal(t, entries[i], *e)
+		validateHeight(t, queue)
+	}
+	require.Nil(t, queue)
+
+	require.Equal(t, entries[1:], tree.DeleteMin().Entries())
+	require.Equal(t, entries[:len(entries)-1], tree.DeleteMax().Entries())
+	require.Equal(t, entries, tree.Entries()) // persistence
+}
+
+func TestPopBuiltin(t *testing.T) {
+	tree := NewBuiltin[int, string]().Insert(2, "b").Insert(1, "a").Insert(3, "c")
+	min, rest := tree.PopMin()
+	require.Equal(t, &Entry[int, string]{1, "a"}, min)
+	require.Equal(t, 2, rest.Len())
+	max, rest := rest.PopMax()
+	require.Equal(t, &Entry[int, string]{3, "c"}, max)
+	require.Equal(t, []Entry[int, string]{{2, "b"}}, rest.Entries())
+	require.Equal(t, []Entry[int, string]{{2, "b"}, {3, "c"}}, tree.DeleteMin().Entries())
+	require.Equal(t, []Entry[int, string]{{1, "a"}, {2, "b"}}, tree.DeleteMax().Entries())
+
+	min, rest = NewBuiltin[int, string]().PopMin()
+	require.Nil(t, min)
+	require.Equal(t, 0, rest.Len())
+}
+
+func TestRemoveRange(t *testing.T) {
+	var tree *Node[Builtin[int], int]
+	N := 60
+	for _, i := range rand.Perm(N) {
+		tree = tree.Insert(Builtin[int]{i * 2}, i*2) // even keys only
+	}
+	entries := tree.Entries()
+
+	c := nodeCore[Builtin[int], int]()
+	bounds := []Bound{Inclusive, Exclusive, Unbounded}
+	for _, loBound := range bounds {
+		for _, hiBound := range bounds {
+			opts := RangeOptions{Lo: loBound, Hi: hiBound}
+			for lo := -1; lo <= N*2; lo += 3 {
+				for hi := lo - 2; hi <= N*2+1; hi += 5 {
+					expected := []Entry[Builtin[int], int]{}
+					for _, e := range entries {
+						if !c.aboveLower(e.K, Builtin[int]{lo}, loBound) || !c.belowUpper(e.K, Builtin[int]{hi}, hiBound) {
+							expected = append(expected, e)
+						}
+					}
+					removed := tree.RemoveRange(Builtin[int]{lo}, Builtin[int]{hi}, opts)
+					validateHeight(t, removed)
+					validateOrdered(t, removed)
+					require.Equal(t, expected, removed.Entries(), "%v [%v, %v]", opts, lo, hi)
+					require.Equal(t, len(expected), removed.Len())
+					if len(expected) == len(entries) {
+						require.Same(t, tree, removed)
+					}
+				}
+			}
+		}
+	}
+	require.Equal(t, entries, tree.Entries()) // persistence
+
+	for k := -1; k <= N*2; k++ {
+		before := tree.TruncateBefore(Builtin[int]{k})
+		after := tree.TruncateAfter(Builtin[int]{k})
+		validateHeight(t, before)
+		validateHeight(t, after)
+		pivot := min((k+1)/2, N) // number of keys < k
+		require.Equal(t, entries[pivot:], before.Entries())
+		if k >= 0 && k%2 == 0 && k < N*2 {
+			pivot += 1 // k itself is kept
+		}
+		require.Equal(t, entries[:pivot], after.Entries())
+	}
+	require.Same(t, tree, tree.TruncateBefore(Builtin[int]{0}))
+	require.Same(t, tree, tree.TruncateAfter(Builtin[int]{N * 2}))
+}
+
+func TestRemoveRangeBuiltin(t *testing.T) {
+	tree := NewBuiltin[int, int]()
+	for i := range 10 {
+		tree = tree.Insert(i, i)
+	}
+	keys := func(tree NodeBuiltin[int, int]) []int {
+		var keys []int
+		for k := range tree.All() {
+			keys = append(keys, k)
+		}
+		return keys
+	}
+	require.Equal(t, []int{0, 1, 2, 7, 8, 9}, keys(tree.RemoveRange(3, 7, RangeOptions{Hi: Exclusive})))
+	require.Equal(t, []int{5, 6, 7, 8, 9}, keys(tree.TruncateBefore(5)))
+	require.Equal(t, []int{0, 1, 2, 3, 4, 5}, keys(tree.TruncateAfter(5)))
+}
+
+func TestKeysValues(t *testing.T) {
+	tree, model := randomTree(200, 500)
+	entries := modelEntries(model)
+	var keys []Builtin[int]
+	var values []int
+	for _, e := range entries {
+		keys = append(keys, e.K)
+		values = append(values, e.V)
+	}
+	require.Equal(t, keys, slices.Collect(tree.Keys()))
+	require.Equal(t, values, slices.Collect(tree.Values()))
+	require.Equal(t, keys, tree.KeysSlice())
+	require.Equal(t, values, tree.ValuesSlice())
+
+	opts := RangeOptions{Hi: Exclusive}
+	var rangeKeys []Builtin[int]
+	var rangeValues []int
+	for k, v := range tree.Range(Builtin[int]{100}, Builtin[int]{300}, opts) {
+		rangeKeys = append(rangeKeys, k)
+		rangeValues = append(rangeValues, v)
+	}
+	require.Equal(t, rangeKeys, slices.Collect(tree.KeysRange(Builtin[int]{100}, Builtin[int]{300}, opts)))
+	require.Equal(t, rangeValues, slices.Collect(tree.ValuesRange(Builtin[int]{100}, Builtin[int]{300}, opts)))
+
+	for range tree.Keys() {
+		break // early termination
+	}
+	for range tree.Values() {
+		break
+	}
+
+	var empty *Node[Builtin[int], int]
+	require.Empty(t, empty.KeysSlice())
+	require.Empty(t, empty.ValuesSlice())
+}
+
+func TestKeysValuesBuiltin(t *testing.T) {
+	tree := NewBuiltin[int, string]()
+	for _, i := range rand.Perm(10) {
+		tree = tree.Insert(i, fmt.Sprint(i))
+	}
+	require.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, tree.KeysSlice())
+	require.Equal(t, []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}, tree.ValuesSlice())
+	require.Equal(t, tree.KeysSlice(), slices.Collect(tree.Keys()))
+	require.Equal(t, tree.ValuesSlice(), slices.Collect(tree.Values()))
+	require.Equal(t, []int{3, 4, 5}, slices.Collect(tree.KeysRange(3, 5, RangeOptions{})))
+	require.Equal(t, []string{"4", "5"}, slices.Collect(tree.ValuesRange(3, 5, RangeOptions{Lo: Exclusive})))
+}
+
+func TestBuiltinParity(t *testing.T) {
+	// every method of the generic types needs a counterpart on the builtin wrapper,
+	// and on each of the other map variants
+	for base, variants := range map[reflect.Type][]reflect.Type{
+		reflect.TypeFor[*Node[Builtin[int], int]](): {
+			reflect.TypeFor[NodeBuiltin[int, int]](),
+			reflect.TypeFor[*NodeOrdered[int, int]](),
+			reflect.TypeFor[NodeFunc[int, int]](),
+			reflect.TypeFor[NodeMerkle[Builtin[int], int]](),
+		},
+		reflect.TypeFor[*Builder[Builtin[int], int]](): {
+			reflect.TypeFor[*BuilderBuiltin[int, int]](),
+			reflect.TypeFor[*BuilderOrdered[int, int]](),
+			reflect.TypeFor[*BuilderFunc[int, int]](),
+			reflect.TypeFor[*BuilderMerkle[Builtin[int], int]](),
+		},
+		reflect.TypeFor[*Cursor[Builtin[int], int]](): {
+			reflect.TypeFor[*CursorBuiltin[int, int]](),
+			reflect.TypeFor[*CursorOrdered[int, int]](),
+			reflect.TypeFor[*CursorFunc[int, int]](),
+			reflect.TypeFor[*CursorMerkle[Builtin[int], int]](),
+		},
+	} {
+		for _, variant := range variants {
+			for i := range base.NumMethod() {
+				method := base.Method(i)
+				wrapped, ok := variant.MethodByName(method.Name)
+				require.True(t, ok, "%v lacks %v", variant, method.Name)
+				require.Equal(t, method.Type.NumIn(), wrapped.Type.NumIn(), "%v.%v", variant, method.Name)
+				require.Equal(t, method.Type.NumOut(), wrapped.Type.NumOut(), "%v.%v", variant, method.Name)
+			}
+		}
+	}
+
+	// and every top-level function taking or returning a Node needs a Builtin variant,
+	// either as a function or as a method where the constraints allow it
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	require.NoError(t, err)
+	funcs := map[string]*ast.FuncDecl{}
+	for _, file := range pkgs["ordmap"].Files {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.IsExported() {
+				funcs[fn.Name.Name] = fn
+			}
+		}
+	}
+	for name, fn := range funcs {
+		usesNode := false
+		ast.Inspect(fn.Type, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok && ident.Name == "Node" {
+				usesNode = true
+			}
+			return !usesNode
+		})
+		if usesNode && !strings.HasSuffix(name, "Builtin") {
+			_, ok := funcs[name+"Builtin"]
+			if !ok {
+				_, ok = reflect.TypeFor[NodeBuiltin[int, int]]().MethodByName(name)
+			}
+			require.True(t, ok, "%vBuiltin is missing", name)
+		}
+	}
+}
+
+func TestMakeBuiltin(t *testing.T) {
+	m := FromSortedBuiltin([]Entry[int, string]{{1, "a"}, {2, "b"}})
+	// keys of the underlying Node can be built and read without reaching into Builtin
+	node := m.Node().Insert(MakeBuiltin(3), "c")
+	v, ok := node.Get(MakeBuiltin(2))
+	require.True(t, ok)
+	require.Equal(t, "b", v)
+	require.Equal(t, 3, node.Max().K.Value())
+	require.Equal(t, []int{1, 2, 3}, WrapBuiltin(node).KeysSlice())
+}
+
+func TestTemplateSelfContained(t *testing.T) {
+	// Template is avl.go on its own, so it must not depend on the other files.
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "avl.go", Template, 0)
+	require.NoError(t, err)
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err = conf.Check("ordmap", fset, []*ast.File{file}, nil)
+	require.NoError(t, err)
+}
+
 func TestEmptyLen(t *testing.T) {
 	var empty *Node[Builtin[int], int]
 	require.Equal(t, 0, empty.Len())
@@ -537,6 +1363,89 @@ func BenchmarkTree(b *testing.B) {
 					}
 				}
 			})
+			b.Run("First5", func(b *testing.B) {
+				b.ReportAllocs()
+				for range b.N {
+					count := 0
+					for k, v := range tree.All() {
+						_, _ = k, v
+						count += 1
+						if count >= 5 {
+							break
+						}
+					}
+				}
+			})
+			b.Run("Backward", func(b *testing.B) {
+				b.ReportAllocs()
+				for range b.N {
+					for k, v := range tree.Backward() {
+						_, _ = k, v
+					}
+				}
+			})
+			b.Run("From", func(b *testing.B) {
+				b.ReportAllocs()
+				for range b.N {
+					for k, v := range tree.From(Builtin[int]{M / 2}) {
+						_, _ = k, v
+					}
+				}
+			})
+			b.Run("From5", func(b *testing.B) {
+				b.ReportAllocs()
+				for range b.N {
+					count := 0
+					for k, v := range tree.From(Builtin[int]{M / 2}) {
+						_, _ = k, v
+						count += 1
+						if count >= 5 {
+							break
+						}
+					}
+				}
+			})
+			b.Run("BackwardFrom5", func(b *testing.B) {
+				b.ReportAllocs()
+				for range b.N {
+					count := 0
+					for k, v := range tree.BackwardFrom(Builtin[int]{M / 2}) {
+						_, _ = k, v
+						count += 1
+						if count >= 5 {
+							break
+						}
+					}
+				}
+			})
+			b.Run("Range", func(b *testing.B) {
+				b.ReportAllocs()
+				for range b.N {
+					for k, v := range tree.Range(Builtin[int]{M / 4}, Builtin[int]{3 * M / 4}, RangeOptions{}) {
+						_, _ = k, v
+					}
+				}
+			})
+			b.Run("BackwardRange5", func(b *testing.B) {
+				b.ReportAllocs()
+				for range b.N {
+					count := 0
+					for k, v := range tree.BackwardRange(Builtin[int]{M / 4}, Builtin[int]{3 * M / 4}, RangeOptions{}) {
+						_, _ = k, v
+						count += 1
+						if count >= 5 {
+							break
+						}
+					}
+				}
+			})
+			b.Run("FromSorted", func(b *testing.B) {
+				entries := tree.Entries()
+				b.ReportAllocs()
+				for range b.N {
+					FromSorted(entries)
+				}
+			})
 			b.Run("Min", func(b *testing.B) {
 				b.ReportAllocs()
 				for range b.N {
@@ -0,0 +1,242 @@
+package ordmap
+
+// Builder is a mutable (transient) view of a map used for efficient batch construction.
+// Unlike Node operations, Builder updates nodes it has allocated itself in place
+// instead of copying the whole path, while nodes shared with the source map are
+// copied on first write. Freeze turns the result back into an ordinary persistent map.
+//
+// A Builder is not safe for concurrent use.
+type Builder[K Comparable[K], V any] struct {
+	builder[K, V, struct{}, lessOrder[K, V, struct{}]]
+}
+
+// Builder returns a Builder starting out with the contents of the map.
+// The map itself is never modified.
+func (node *Node[K, V]) Builder() *Builder[K, V] {
+	return &Builder[K, V]{newBuilder(nodeCore[K, V](), node.tree())}
+}
+
+// Freeze returns the current contents of the builder as a persistent map,
+// sharing untouched subtrees with the map the builder was created from.
+// The builder remains usable; subsequent writes will no longer affect the returned map.
+func (b *Builder[K, V]) Freeze() *Node[K, V] {
+	return asNode(b.freeze())
+}
+
+// builder implements the builders of all the map types on top of core.
+// The nodes it has allocated since the last freeze are marked as owned.
+type builder[K, V, A any, O ordering[K, V, A]] struct {
+	c    core[K, V, A, O]
+	root *tree[K, V, A]
+}
+
+func newBuilder[K, V, A any, O ordering[K, V, A]](c core[K, V, A, O], root *tree[K, V, A]) builder[K, V, A, O] {
+	return builder[K, V, A, O]{c: c, root: root}
+}
+
+func (b *builder[K, V, A, O]) freeze() *tree[K, V, A] {
+	release(b.root)
+	return b.root
+}
+
+// release clears the owned mark of all nodes reachable from node. Owned nodes are
+// only ever reachable through other owned nodes, since the builder never modifies
+// a node it does not own, so this only visits the nodes changed since the last freeze.
+func release[K, V, A any](node *tree[K, V, A]) {
+	for node != nil && node.owned {
+		node.owned = false
+		release(node.children[0])
+		node = node.children[1]
+	}
+}
+
+// Get retrieves the value for the given key.
+// It returns the value and true if the key exists, otherwise the zero value and false.
+func (b *builder[K, V, A, O]) Get(key K) (value V, ok bool) {
+	return b.c.get(b.root, key)
+}
+
+// Len returns the number of elements in the builder.
+func (b *builder[K, V, A, O]) Len() int {
+	return b.root.length()
+}
+
+// Insert adds a key-value pair.
+// If the key already exists, its value is updated.
+func (b *builder[K, V, A, O]) Insert(key K, value V) {
+	b.insert(key, value)
+}
+
+// Remove deletes the key if it exists.
+func (b *builder[K, V, A, O]) Remove(key K) {
+	b.remove(key)
+}
+
+// own returns a node the builder is allowed to mutate: either node itself or a copy.
+func (b *builder[K, V, A, O]) own(node *tree[K, V, A]) *tree[K, V, A] {
+	if node.owned {
+		return node
+	}
+	owned := new(tree[K, V, A])
+	*owned = *node
+	owned.owned = true
+	return owned
+}
+
+// refresh recomputes the cached height, length and augmentation of an owned node.
+func (b *builder[K, V, A, O]) refresh(node *tree[K, V, A]) {
+	left, right := node.children[0], node.children[1]
+	if b.c.augment != nil {
+		node.aug = b.c.augment(node.entry, left, right)
+	}
+	node.h = int32(max(left.height(), right.height()) + 1)
+	node.len = 1 + left.length() + right.length()
+}
+
+// rebalance is the in-place counterpart of rotate: it restores the AVL invariant
+// at an owned node whose children have changed and returns the new subtree root.
+func (b *builder[K, V, A, O]) rebalance(node *tree[K, V, A]) *tree[K, V, A] {
+	left, right := node.children[0], node.children[1]
+	if right.height()-left.height() > 1 { // implies right != nil
+		right = b.own(right)
+		rl := right.children[0]
+		rr := right.children[1]
+		if max(left.height(), rl.height())+1-rr.height() > 1 {
+			// double rotation
+			rl = b.own(rl)
+			node.children[1] = rl.children[0]
+			right.children[0] = rl.children[1]
+			b.refresh(node)
+			b.refresh(right)
+			rl.children = [2]*tree[K, V, A]{node, right}
+			b.refresh(rl)
+			return rl
+		}
+		// single left
+		node.children[1] = rl
+		b.refresh(node)
+		right.children[0] = node
+		b.refresh(right)
+		return right
+	}
+	if left.height()-right.height() > 1 { // implies left != nil
+		left = b.own(left)
+		ll := left.children[0]
+		lr := left.children[1]
+		if max(right.height(), lr.height())+1-ll.height() > 1 {
+			// double rotation
+			lr = b.own(lr)
+			left.children[1] = lr.children[0]
+			node.children[0] = lr.children[1]
+			b.refresh(left)
+			b.refresh(node)
+			lr.children = [2]*tree[K, V, A]{left, node}
+			b.refresh(lr)
+			return lr
+		}
+		// single right
+		node.children[0] = lr
+		b.refresh(node)
+		left.children[1] = node
+		b.refresh(left)
+		return left
+	}
+	b.refresh(node)
+	return node
+}
+
+func (b *builder[K, V, A, O]) insert(key K, value V) {
+	p, found := b.c.ord.search(b.root, key)
+	var w walker[K, V, A]
+	w.follow(b.root, p)
+	if !found {
+		leaf := b.c.mk(Entry[K, V]{key, value}, nil, nil)
+		leaf.owned = true
+		b.root = b.rebuild(&w, p, leaf)
+		return
+	}
+	node := b.own(w.pop())
+	node.entry = Entry[K, V]{key, value}
+	b.refresh(node)
+	b.root = b.rebuild(&w, p, node)
+}
+
+// remove does not take ownership of (and thus copy) the path to a missing key.
+func (b *builder[K, V, A, O]) remove(key K) {
+	p, found := b.c.ord.search(b.root, key)
+	if !found {
+		return
+	}
+	var w walker[K, V, A]
+	w.follow(b.root, p)
+	old := w.pop()
+	sub := old.children[1]
+	if old.children[0] != nil {
+		node := b.own(old)
+		node.children[0], node.entry = b.removeMax(node.children[0])
+		sub = b.rebalance(node)
+	}
+	b.root = b.rebuild(&w, p, sub)
+}
+
+// rebuild is the in-place counterpart of core.rebuild: it takes ownership of the nodes
+// of p left on w, puts sub where the path continues below the top one and rebalances
+// on the way up.
+func (b *builder[K, V, A, O]) rebuild(w *walker[K, V, A], p path, sub *tree[K, V, A]) *tree[K, V, A] {
+	for w.top > 0 {
+		node := b.own(w.pop())
+		node.children[p.dir(w.top)] = sub
+		sub = b.rebalance(node)
+	}
+	return sub
+}
+
+func (b *builder[K, V, A, O]) removeMax(node *tree[K, V, A]) (*tree[K, V, A], Entry[K, V]) {
+	if node.children[1] == nil {
+		return node.children[0], node.entry
+	}
+	node = b.own(node)
+	var max Entry[K, V]
+	node.children[1], max = b.removeMax(node.children[1])
+	return b.rebalance(node), max
+}
+
+// BuilderBuiltin is a Builder for NodeBuiltin.
+// It simplifies usage by handling the Builtin wrapper automatically.
+type BuilderBuiltin[K BuiltinComparable, V any] struct {
+	b *Builder[Builtin[K], V]
+}
+
+// Builder returns a BuilderBuiltin starting out with the contents of the map.
+// The map itself is never modified.
+func (n NodeBuiltin[K, V]) Builder() *BuilderBuiltin[K, V] {
+	return &BuilderBuiltin[K, V]{n.n.Builder()}
+}
+
+// Freeze returns the current contents of the builder as a persistent map.
+// See Builder.Freeze for details.
+func (b *BuilderBuiltin[K, V]) Freeze() NodeBuiltin[K, V] {
+	return NodeBuiltin[K, V]{b.b.Freeze()}
+}
+
+// Get retrieves the value for the given key.
+// It returns the value and true if the key exists, otherwise the zero value and false.
+func (b *BuilderBuiltin[K, V]) Get(key K) (value V, ok bool) {
+	return b.b.Get(Builtin[K]{key})
+}
+
+// Len returns the number of elements in the builder.
+func (b *BuilderBuiltin[K, V]) Len() int {
+	return b.b.Len()
+}
+
+// Insert adds a key-value pair.
+// If the key already exists, its value is updated.
+func (b *BuilderBuiltin[K, V]) Insert(key K, value V) {
+	b.b.Insert(Builtin[K]{key}, value)
+}
+
+// Remove deletes the key if it exists.
+func (b *BuilderBuiltin[K, V]) Remove(key K) {
+	b.b.Remove(Builtin[K]{key})
+}
@@ -0,0 +1,196 @@
+package ordmap
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder(t *testing.T) {
+	source, model := randomTree(300, 500)
+	sourceEntries := source.Entries()
+
+	b := source.Builder()
+	N := 500
+	for range N * 3 {
+		k := rand.Intn(N)
+		if rand.Float64() < 0.6 {
+			v := rand.Int()
+			b.Insert(Builtin[int]{k}, v)
+			model[k] = v
+		} else {
+			b.Remove(Builtin[int]{k})
+			delete(model, k)
+		}
+		require.Equal(t, len(model), b.Len())
+	}
+	for k, v := range model {
+		value, ok := b.Get(Builtin[int]{k})
+		require.True(t, ok)
+		require.Equal(t, v, value)
+	}
+
+	frozen := b.Freeze()
+	validateHeight(t, frozen)
+	validateOrdered(t, frozen)
+	require.Equal(t, modelEntries(model), frozen.Entries())
+	require.Equal(t, sourceEntries, source.Entries()) // source untouched
+	var w walker[Builtin[int], int, struct{}]
+	w.pushSpine(frozen.tree(), 0)
+	for n := w.next(1); n != nil; n = w.next(1) {
+		require.False(t, n.owned, "frozen node %v is still owned", n.entry.K)
+	}
+
+	// the builder remains usable, but no longer affects the frozen map
+	frozenEntries := frozen.Entries()
+	for k := range N {
+		b.Remove(Builtin[int]{k})
+	}
+	b.Insert(Builtin[int]{-1}, -1)
+	require.Equal(t, 1, b.Len())
+	require.Equal(t, frozenEntries, frozen.Entries())
+	require.Equal(t, sourceEntries, source.Entries())
+}
+
+func TestBuilderSharing(t *testing.T) {
+	var source *Node[Builtin[int], int]
+	for i := range 100 {
+		source = source.Insert(Builtin[int]{i}, i)
+	}
+
+	b := source.Builder()
+	b.Remove(Builtin[int]{1000}) // missing key copies nothing
+	require.Same(t, source, b.Freeze())
+
+	b.Insert(Builtin[int]{1000}, 1000) // only touches the right spine
+	frozen := b.Freeze()
+	require.Same(t, source.children[0], frozen.children[0])
+
+	var empty *Node[Builtin[int], int]
+	b = empty.Builder()
+	b.Remove(Builtin[int]{0})
+	require.Nil(t, b.Freeze())
+}
+
+func TestBuilderSequential(t *testing.T) {
+	// ascending and descending inserts exercise both single rotations,
+	// zig-zag inserts the double ones
+	for _, keys := range [][]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		{9, 8, 7, 6, 5, 4, 3, 2, 1, 0},
+		{0, 9, 1, 8, 2, 7, 3, 6, 4, 5},
+		{5, 4, 6, 3, 7, 2, 8, 1, 9, 0},
+	} {
+		b := New[Builtin[int], int]().Builder()
+		for _, k := range keys {
+			b.Insert(Builtin[int]{k}, k)
+			b.Insert(Builtin[int]{k}, k) // overwrite
+		}
+		tree := b.Freeze()
+		validateHeight(t, tree)
+		require.Equal(t, 10, tree.Len())
+		for _, k := range keys {
+			b.Remove(Builtin[int]{k})
+			validateTree(t, b.c, b.root)
+		}
+		require.Equal(t, 0, b.Len())
+	}
+}
+
+func TestBuilderBuiltin(t *testing.T) {
+	source := NewBuiltin[int, int]()
+	for i := range 100 {
+		source = source.Insert(i, i)
+	}
+	b := source.Builder()
+	for i := range 50 {
+		b.Remove(i * 2)
+	}
+	b.Insert(1000, 1000)
+	value, ok := b.Get(1000)
+	require.True(t, ok)
+	require.Equal(t, 1000, value)
+	require.Equal(t, 51, b.Len())
+
+	frozen := b.Freeze()
+	validateHeight(t, frozen.Node())
+	validateOrdered(t, frozen.Node())
+	require.Equal(t, 51, frozen.Len())
+	require.Equal(t, 100, source.Len())
+	_, ok = frozen.Get(2)
+	require.False(t, ok)
+}
+
+func BenchmarkBuilder(b *testing.B) {
+	for _, M := range []int{100, 10000, 100000} {
+		b.Run(fmt.Sprintf("%v", M), func(b *testing.B) {
+			b.Run("Insert", func(b *testing.B) {
+				b.ReportAllocs()
+				for range b.N {
+					var tree *Node[Builtin[int], int]
+					for i := range M {
+						tree = tree.Insert(Builtin[int]{i}, i)
+					}
+				}
+			})
+			b.Run("Builder", func(b *testing.B) {
+				b.ReportAllocs()
+				for range b.N {
+					builder := New[Builtin[int], int]().Builder()
+					for i := range M {
+						builder.Insert(Builtin[int]{i}, i)
+					}
+					builder.Freeze()
+				}
+			})
+			keys := rand.Perm(M)
+			b.Run("InsertShuffled", func(b *testing.B) {
+				b.ReportAllocs()
+				for range b.N {
+					var tree *Node[Builtin[int], int]
+					for _, k := range keys {
+						tree = tree.Insert(Builtin[int]{k}, k)
+					}
+				}
+			})
+			b.Run("BuilderShuffled", func(b *testing.B) {
+				b.ReportAllocs()
+				for range b.N {
+					builder := New[Builtin[int], int]().Builder()
+					for _, k := range keys {
+						builder.Insert(Builtin[int]{k}, k)
+					}
+					builder.Freeze()
+				}
+			})
+			// replace every key of an existing map, which a long-lived builder sees
+			source := FromSeq(func(yield func(Builtin[int], int) bool) {
+				for k := range M {
+					yield(Builtin[int]{k}, k)
+				}
+			})
+			b.Run("InsertChurn", func(b *testing.B) {
+				b.ReportAllocs()
+				for range b.N {
+					tree := source
+					for _, k := range keys {
+						tree = tree.Remove(Builtin[int]{k}).Insert(Builtin[int]{k + M}, k)
+					}
+				}
+			})
+			b.Run("BuilderChurn", func(b *testing.B) {
+				b.ReportAllocs()
+				for range b.N {
+					builder := source.Builder()
+					for _, k := range keys {
+						builder.Remove(Builtin[int]{k})
+						builder.Insert(Builtin[int]{k + M}, k)
+					}
+					builder.Freeze()
+				}
+			})
+		})
+	}
+}
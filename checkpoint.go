@@ -0,0 +1,248 @@
+package ordmap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// A checkpoint holds the nodes of a version that were not written by earlier checkpoints.
+// Nodes are numbered consecutively across checkpoints starting from 1, with 0 standing
+// for an empty subtree, so the nodes in a checkpoint need not carry their own ids.
+// It is laid out as follows, with all integers but the checksum encoded as uvarints:
+//
+//	magic     "ordc"
+//	version   1 byte
+//	first     id of the first node in this checkpoint
+//	count     number of nodes in this checkpoint
+//	nodes     count times, children before parents: left id, right id,
+//	          key length, key, value length, value
+//	root      id of the root of the version
+//	checksum  CRC-32C of everything above, 4 bytes little endian
+const (
+	checkpointMagic   = "ordc"
+	checkpointVersion = 1
+)
+
+// Checkpointer persists successive versions of a map, writing only the nodes
+// that are not shared with the previously written version. For a large map that
+// changes by a few keys between checkpoints these deltas stay small: O(changes * log N).
+//
+// The first checkpoint written (or restored) is the base and holds the whole map.
+// To load a version, Restore the base and then every later checkpoint in order.
+// Since reloading replays all of them, it pays off to start over with a new
+// Checkpointer and a new base from time to time.
+//
+// A Checkpointer remembers the nodes of the last version it has seen, which costs
+// memory proportional to the size of the map.
+type Checkpointer[K Comparable[K], V any] struct {
+	keys    Codec[K]
+	values  Codec[V]
+	root    *Node[K, V]
+	ids     map[*Node[K, V]]uint64 // nodes of root, by the id they were written under
+	nodes   map[uint64]*Node[K, V] // the inverse of ids, only kept once restoring
+	next    uint64
+	buf     []byte
+	scratch []byte
+}
+
+// NewCheckpointer returns a checkpointer with no versions written yet.
+func NewCheckpointer[K Comparable[K], V any](keys Codec[K], values Codec[V]) *Checkpointer[K, V] {
+	return &Checkpointer[K, V]{
+		keys:   keys,
+		values: values,
+		ids:    map[*Node[K, V]]uint64{},
+		next:   1,
+	}
+}
+
+// Checkpoint writes the nodes of node not written by previous checkpoints to w.
+// If writing fails, the checkpoint does not count and the next one is based on
+// the same version as this one would have been.
+func (c *Checkpointer[K, V]) Checkpoint(w io.Writer, node *Node[K, V]) error {
+	cw := newChecksumWriter(w)
+	c.buf = append(c.buf[:0], checkpointMagic...)
+	c.buf = append(c.buf, checkpointVersion)
+	c.buf = binary.AppendUvarint(c.buf, c.next)
+	c.buf = binary.AppendUvarint(c.buf, uint64(c.countNew(node)))
+	if err := cw.write(c.buf); err != nil {
+		return err
+	}
+	var added []*Node[K, V]
+	shared := map[*Node[K, V]]bool{}
+	root, err := c.writeNew(&cw, node, &added, shared)
+	if err != nil {
+		return err
+	}
+	c.buf = binary.AppendUvarint(c.buf[:0], root)
+	if err := cw.write(c.buf); err != nil {
+		return err
+	}
+	if err := cw.finish(); err != nil {
+		return err
+	}
+	c.commit(node, added, shared)
+	return nil
+}
+
+// countNew counts the nodes of node not written yet. Written subtrees are complete,
+// so there is no need to look inside them.
+func (c *Checkpointer[K, V]) countNew(node *Node[K, V]) int {
+	if node == nil {
+		return 0
+	}
+	if _, ok := c.ids[node]; ok {
+		return 0
+	}
+	return 1 + c.countNew(node.child(0)) + c.countNew(node.child(1))
+}
+
+// writeNew writes the nodes of node not written yet, children first, and returns its id.
+// New nodes are collected in added, in order of their ids, and the roots of subtrees
+// written previously in shared.
+func (c *Checkpointer[K, V]) writeNew(w *checksumWriter, node *Node[K, V], added *[]*Node[K, V], shared map[*Node[K, V]]bool) (uint64, error) {
+	if node == nil {
+		return 0, nil
+	}
+	if id, ok := c.ids[node]; ok {
+		shared[node] = true
+		return id, nil
+	}
+	left, err := c.writeNew(w, node.child(0), added, shared)
+	if err != nil {
+		return 0, err
+	}
+	right, err := c.writeNew(w, node.child(1), added, shared)
+	if err != nil {
+		return 0, err
+	}
+	c.buf = binary.AppendUvarint(c.buf[:0], left)
+	c.buf = binary.AppendUvarint(c.buf, right)
+	if c.buf, c.scratch, err = appendRecord(c.buf, c.scratch, c.keys, node.entry.K); err != nil {
+		return 0, err
+	}
+	if c.buf, c.scratch, err = appendRecord(c.buf, c.scratch, c.values, node.entry.V); err != nil {
+		return 0, err
+	}
+	if err := w.write(c.buf); err != nil {
+		return 0, err
+	}
+	*added = append(*added, node)
+	return c.next + uint64(len(*added)) - 1, nil
+}
+
+// Restore reads a checkpoint written by Checkpoint and returns the version it holds.
+// Checkpoints have to be restored in the order they were written, starting with the base,
+// and afterwards Checkpoint continues the sequence with deltas on top of the restored version.
+// Reads are buffered unless r is a *bufio.Reader already, so r should not hold anything
+// after the checkpoint.
+func (c *Checkpointer[K, V]) Restore(r io.Reader) (*Node[K, V], error) {
+	cr := newChecksumReader(r)
+	header, err := cr.read(len(checkpointMagic) + 1)
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	if string(header[:len(checkpointMagic)]) != checkpointMagic {
+		return nil, fmt.Errorf("%w: bad magic", ErrInvalidSnapshot)
+	}
+	if version := header[len(checkpointMagic)]; version != checkpointVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidSnapshot, version)
+	}
+	first, err := cr.readUvarint()
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	if first != c.next {
+		return nil, fmt.Errorf("%w: checkpoint starts at node %d, expected %d", ErrInvalidSnapshot, first, c.next)
+	}
+	count, err := cr.readUvarint()
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	if c.nodes == nil {
+		c.nodes = make(map[uint64]*Node[K, V], len(c.ids))
+		for node, id := range c.ids {
+			c.nodes[id] = node
+		}
+	}
+
+	var added []*Node[K, V]
+	shared := map[*Node[K, V]]bool{}
+	lookup := func() (*Node[K, V], error) {
+		id, err := cr.readUvarint()
+		switch {
+		case err != nil:
+			return nil, err
+		case id == 0:
+			return nil, nil
+		case id >= first && id-first < uint64(len(added)):
+			return added[id-first], nil
+		case id < first && c.nodes[id] != nil:
+			shared[c.nodes[id]] = true
+			return c.nodes[id], nil
+		default:
+			return nil, fmt.Errorf("%w: unknown node %d", ErrInvalidSnapshot, id)
+		}
+	}
+	for range count {
+		left, err := lookup()
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		right, err := lookup()
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		var entry Entry[K, V]
+		if entry.K, err = readRecord(&cr, c.keys); err != nil {
+			return nil, noEOF(err)
+		}
+		if entry.V, err = readRecord(&cr, c.values); err != nil {
+			return nil, noEOF(err)
+		}
+		if diff := left.height() - right.height(); diff < -1 || diff > 1 ||
+			left != nil && !left.Max().K.Less(entry.K) ||
+			right != nil && !entry.K.Less(right.Min().K) {
+			return nil, fmt.Errorf("%w: malformed node %d", ErrInvalidSnapshot, first+uint64(len(added)))
+		}
+		added = append(added, mk_OrdMap(entry, left, right))
+	}
+	root, err := lookup()
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	if err := cr.verify(); err != nil {
+		return nil, noEOF(err)
+	}
+	c.commit(root, added, shared)
+	return root, nil
+}
+
+// commit makes root the last version seen, given the nodes it added to the previous one
+// and the roots of the subtrees it shares with it.
+func (c *Checkpointer[K, V]) commit(root *Node[K, V], added []*Node[K, V], shared map[*Node[K, V]]bool) {
+	c.forget(c.root, shared)
+	for _, node := range added {
+		c.ids[node] = c.next
+		if c.nodes != nil {
+			c.nodes[c.next] = node
+		}
+		c.next++
+	}
+	c.root = root
+}
+
+// forget drops the nodes of the previous version that are not shared with the new one.
+// Shared nodes can only be reached through the roots of shared subtrees, so this
+// only visits the nodes that were replaced.
+func (c *Checkpointer[K, V]) forget(node *Node[K, V], shared map[*Node[K, V]]bool) {
+	if node == nil || shared[node] {
+		return
+	}
+	if c.nodes != nil {
+		delete(c.nodes, c.ids[node])
+	}
+	delete(c.ids, node)
+	c.forget(node.child(0), shared)
+	c.forget(node.child(1), shared)
+}
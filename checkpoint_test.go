@@ -0,0 +1,135 @@
+package ordmap
+
+import (
+	"bytes"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpoint(t *testing.T) {
+	tree, _ := randomTree(5000, 10000)
+	c := NewCheckpointer(intCodec, IntCodec[int]{})
+	var files [][]byte
+	var versions []*Node[Builtin[int], int]
+	checkpoint := func(tree *Node[Builtin[int], int]) {
+		var buf bytes.Buffer
+		require.NoError(t, c.Checkpoint(&buf, tree))
+		files = append(files, buf.Bytes())
+		versions = append(versions, tree)
+		require.Len(t, c.ids, tree.Len(), "only the last version is remembered")
+	}
+	checkpoint(tree)
+	base := len(files[0])
+	for i := range 20 {
+		for range i {
+			k := rand.Intn(10000)
+			if rand.Intn(3) == 0 {
+				tree = tree.Remove(Builtin[int]{k})
+			} else {
+				tree = tree.Insert(Builtin[int]{k}, rand.Int())
+			}
+		}
+		checkpoint(tree)
+		// each change rewrites at most a path and a few rotated nodes
+		require.Less(t, len(files[len(files)-1]), 64+i*(tree.height()+2)*24)
+	}
+	require.Less(t, len(files[1]), 16, "no changes, no nodes")
+
+	restore := func(files [][]byte) (*Checkpointer[Builtin[int], int], *Node[Builtin[int], int]) {
+		r := NewCheckpointer(intCodec, IntCodec[int]{})
+		var prev *Node[Builtin[int], int]
+		for i, file := range files {
+			restored, err := r.Restore(bytes.NewReader(file))
+			require.NoError(t, err)
+			validateHeight(t, restored)
+			validateOrdered(t, restored)
+			require.Equal(t, versions[i].Entries(), restored.Entries())
+			require.Len(t, r.ids, restored.Len())
+			require.Len(t, r.nodes, restored.Len())
+			if i > 0 {
+				// restored versions share structure just like the originals:
+				// with values never equal, every entry that is not shared is reported
+				never := func(a, b int) bool { return false }
+				require.Equal(t, len(slices.Collect(DiffFunc(versions[i-1], versions[i], never))), len(slices.Collect(DiffFunc(prev, restored, never))))
+			}
+			prev = restored
+		}
+		return r, prev
+	}
+	r, restored := restore(files)
+
+	// a restored checkpointer continues where the original left off
+	tree = tree.Insert(Builtin[int]{-1}, -1)
+	var buf, restoredBuf bytes.Buffer
+	require.NoError(t, c.Checkpoint(&buf, tree))
+	require.NoError(t, r.Checkpoint(&restoredBuf, restored.Insert(Builtin[int]{-1}, -1)))
+	require.Equal(t, buf.Bytes(), restoredBuf.Bytes())
+	versions = append(versions, tree)
+	restore(append(files, buf.Bytes()))
+	require.Greater(t, base, 5*len(buf.Bytes()))
+}
+
+func TestCheckpointErrors(t *testing.T) {
+	tree, _ := randomTree(100, 200)
+	c := NewCheckpointer(intCodec, IntCodec[int]{})
+	require.ErrorContains(t, c.Checkpoint(failingWriter{}, tree), "disk full")
+	var base bytes.Buffer
+	require.NoError(t, c.Checkpoint(&base, tree))
+	changed := tree.Insert(Builtin[int]{1000}, 0)
+	var delta, other bytes.Buffer
+	require.NoError(t, c.Checkpoint(&delta, changed))
+	require.NoError(t, c.Checkpoint(&other, changed.Remove(Builtin[int]{1000})))
+
+	// the failed write did not count, so the base restores on its own
+	r := NewCheckpointer(intCodec, IntCodec[int]{})
+	_, err := r.Restore(bytes.NewReader(delta.Bytes()))
+	require.ErrorContains(t, err, "checkpoint starts at node")
+	restored, err := r.Restore(bytes.NewReader(base.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, tree.Entries(), restored.Entries())
+	_, err = r.Restore(bytes.NewReader(other.Bytes()))
+	require.ErrorIs(t, err, ErrInvalidSnapshot, "skipped a delta")
+	_, err = r.Restore(bytes.NewReader(base.Bytes()))
+	require.ErrorIs(t, err, ErrInvalidSnapshot, "base twice")
+
+	for n, file := range [][]byte{base.Bytes(), delta.Bytes()} {
+		for i := range file {
+			r := NewCheckpointer(intCodec, IntCodec[int]{})
+			if n > 0 {
+				_, err := r.Restore(bytes.NewReader(base.Bytes()))
+				require.NoError(t, err)
+			}
+			_, err := r.Restore(bytes.NewReader(file[:i]))
+			require.Error(t, err, "truncated to %v", i)
+
+			corrupt := bytes.Clone(file)
+			corrupt[i] ^= 1 << (i % 8)
+			_, err = r.Restore(bytes.NewReader(corrupt))
+			require.Error(t, err, "corrupted at %v", i)
+		}
+	}
+	// failed restores do not count either
+	_, err = r.Restore(bytes.NewReader(delta.Bytes()))
+	require.NoError(t, err)
+}
+
+func BenchmarkCheckpoint(b *testing.B) {
+	var tree *Node[Builtin[int], int]
+	for i := range 100000 {
+		tree = tree.Insert(Builtin[int]{i}, i)
+	}
+	c := NewCheckpointer(intCodec, IntCodec[int]{})
+	var buf bytes.Buffer
+	require.NoError(b, c.Checkpoint(&buf, tree))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		tree = tree.Insert(Builtin[int]{rand.Intn(100000)}, i)
+		buf.Reset()
+		c.Checkpoint(&buf, tree)
+	}
+	b.ReportMetric(float64(buf.Len()), "bytes/delta")
+}
@@ -0,0 +1,138 @@
+package ordmap
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+)
+
+// Codec converts keys or values to and from bytes for snapshots.
+type Codec[T any] interface {
+	// Append appends the encoding of v to buf and returns the extended buffer.
+	Append(buf []byte, v T) ([]byte, error)
+	// Decode decodes a value from data. It must not retain data, which is reused.
+	Decode(data []byte) (T, error)
+}
+
+var errCodecInvalid = errors.New("ordmap: codec: invalid encoding")
+
+type signed interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64
+}
+
+type unsigned interface {
+	~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// StringCodec encodes strings as their raw bytes.
+type StringCodec[T ~string] struct{}
+
+// Append appends the bytes of v to buf.
+func (StringCodec[T]) Append(buf []byte, v T) ([]byte, error) {
+	return append(buf, v...), nil
+}
+
+// Decode returns data as a string, copying it.
+func (StringCodec[T]) Decode(data []byte) (T, error) {
+	return T(data), nil
+}
+
+// BytesCodec encodes byte slices as themselves.
+type BytesCodec struct{}
+
+// Append appends v to buf.
+func (BytesCodec) Append(buf []byte, v []byte) ([]byte, error) {
+	return append(buf, v...), nil
+}
+
+// Decode returns a copy of data, as the codec must not retain it.
+func (BytesCodec) Decode(data []byte) ([]byte, error) {
+	return append([]byte(nil), data...), nil
+}
+
+// IntCodec encodes signed integers as zig-zag varints, so small magnitudes take few bytes.
+type IntCodec[T signed] struct{}
+
+// Append appends v to buf as a zig-zag varint.
+func (IntCodec[T]) Append(buf []byte, v T) ([]byte, error) {
+	return binary.AppendVarint(buf, int64(v)), nil
+}
+
+// Decode decodes a zig-zag varint spanning all of data.
+// It fails if there are bytes left over or the value does not fit in T.
+func (IntCodec[T]) Decode(data []byte) (T, error) {
+	v, n := binary.Varint(data)
+	if n != len(data) || int64(T(v)) != v {
+		return 0, errCodecInvalid
+	}
+	return T(v), nil
+}
+
+// UintCodec encodes unsigned integers as varints.
+type UintCodec[T unsigned] struct{}
+
+// Append appends v to buf as a varint.
+func (UintCodec[T]) Append(buf []byte, v T) ([]byte, error) {
+	return binary.AppendUvarint(buf, uint64(v)), nil
+}
+
+// Decode decodes a varint spanning all of data.
+// It fails if there are bytes left over or the value does not fit in T.
+func (UintCodec[T]) Decode(data []byte) (T, error) {
+	v, n := binary.Uvarint(data)
+	if n != len(data) || uint64(T(v)) != v {
+		return 0, errCodecInvalid
+	}
+	return T(v), nil
+}
+
+// FloatCodec encodes floating point numbers as their 8 byte IEEE 754 representation.
+type FloatCodec[T ~float32 | ~float64] struct{}
+
+// Append appends v to buf as a little endian float64, even if T is a float32.
+func (FloatCodec[T]) Append(buf []byte, v T) ([]byte, error) {
+	return binary.LittleEndian.AppendUint64(buf, math.Float64bits(float64(v))), nil
+}
+
+// Decode decodes a little endian float64 from exactly 8 bytes of data.
+func (FloatCodec[T]) Decode(data []byte) (T, error) {
+	if len(data) != 8 {
+		return 0, errCodecInvalid
+	}
+	return T(math.Float64frombits(binary.LittleEndian.Uint64(data))), nil
+}
+
+// JSONCodec encodes any value with encoding/json. It is a convenient fallback
+// for composite types, at the cost of size and speed.
+type JSONCodec[T any] struct{}
+
+// Append appends the JSON encoding of v to buf.
+func (JSONCodec[T]) Append(buf []byte, v T) ([]byte, error) {
+	data, err := json.Marshal(v)
+	return append(buf, data...), err
+}
+
+// Decode unmarshals data as JSON into a new T.
+func (JSONCodec[T]) Decode(data []byte) (T, error) {
+	var v T
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
+
+// BuiltinCodec adapts a codec for K to the Builtin[K] keys of a Node,
+// e.g. BuiltinCodec[int]{IntCodec[int]{}}.
+type BuiltinCodec[K BuiltinComparable] struct {
+	Codec Codec[K]
+}
+
+// Append appends the encoding of the wrapped value of v to buf using c.Codec.
+func (c BuiltinCodec[K]) Append(buf []byte, v Builtin[K]) ([]byte, error) {
+	return c.Codec.Append(buf, v.value)
+}
+
+// Decode decodes a value with c.Codec and wraps it.
+func (c BuiltinCodec[K]) Decode(data []byte) (Builtin[K], error) {
+	v, err := c.Codec.Decode(data)
+	return Builtin[K]{v}, err
+}
@@ -0,0 +1,201 @@
+package ordmap
+
+// Cursor is a bidirectional iterator over a map that can change direction and re-seek.
+// It keeps the path from the root to the current entry on an explicit stack sized
+// to the height of the tree, so moving it around does not allocate.
+//
+// A new cursor is not positioned; call First, Last, Seek or SeekBackward before reading.
+// Since the map is persistent, the cursor keeps observing the version it was created from.
+type Cursor[K Comparable[K], V any] struct {
+	cursor[K, V, struct{}, lessOrder[K, V, struct{}]]
+}
+
+// Cursor returns a new unpositioned cursor over the map.
+func (node *Node[K, V]) Cursor() *Cursor[K, V] {
+	return &Cursor[K, V]{newCursor(nodeCore[K, V](), node.tree())}
+}
+
+// cursor implements the cursors of all the map types on top of core.
+type cursor[K, V, A any, O ordering[K, V, A]] struct {
+	c     core[K, V, A, O]
+	root  *tree[K, V, A]
+	stack []*tree[K, V, A] // path from root to the current node, empty if not valid
+}
+
+func newCursor[K, V, A any, O ordering[K, V, A]](c core[K, V, A, O], root *tree[K, V, A]) cursor[K, V, A, O] {
+	return cursor[K, V, A, O]{
+		c:     c,
+		root:  root,
+		stack: make([]*tree[K, V, A], 0, root.height()),
+	}
+}
+
+// Valid reports whether the cursor is positioned at an entry.
+func (c *cursor[K, V, A, O]) Valid() bool {
+	return len(c.stack) > 0
+}
+
+// Key returns the key at the current position, or the zero value if the cursor is not valid.
+func (c *cursor[K, V, A, O]) Key() (key K) {
+	if len(c.stack) == 0 {
+		return
+	}
+	return c.stack[len(c.stack)-1].entry.K
+}
+
+// Value returns the value at the current position, or the zero value if the cursor is not valid.
+func (c *cursor[K, V, A, O]) Value() (value V) {
+	if len(c.stack) == 0 {
+		return
+	}
+	return c.stack[len(c.stack)-1].entry.V
+}
+
+// First moves the cursor to the smallest key.
+// Returns false if the map is empty.
+func (c *cursor[K, V, A, O]) First() bool {
+	c.stack = c.stack[:0]
+	c.descend(c.root, 0)
+	return c.Valid()
+}
+
+// Last moves the cursor to the largest key.
+// Returns false if the map is empty.
+func (c *cursor[K, V, A, O]) Last() bool {
+	c.stack = c.stack[:0]
+	c.descend(c.root, 1)
+	return c.Valid()
+}
+
+// Seek moves the cursor to the first key >= key.
+// Returns false if there is no such key.
+func (c *cursor[K, V, A, O]) Seek(key K) bool {
+	return c.seek(key, 1)
+}
+
+// SeekBackward moves the cursor to the last key <= key.
+// Returns false if there is no such key.
+func (c *cursor[K, V, A, O]) SeekBackward(key K) bool {
+	return c.seek(key, 0)
+}
+
+// seek moves the cursor to the closest key at or past key in direction dir
+// (1 ascending, 0 descending).
+func (c *cursor[K, V, A, O]) seek(key K, dir int) bool {
+	p, found := c.c.ord.search(c.root, key)
+	// the target is the deepest node on the path that is at key or ahead of it
+	c.stack = c.stack[:0]
+	target := 0
+	for i, node := 0, c.root; i < p.len; i, node = i+1, node.children[p.dir(i)] {
+		c.stack = append(c.stack, node)
+		if p.dir(i) != dir || found && i == p.len-1 {
+			target = i + 1
+		}
+	}
+	c.stack = c.stack[:target]
+	return c.Valid()
+}
+
+// Next moves the cursor to the next larger key.
+// Returns false (and invalidates the cursor) when moving past the end.
+func (c *cursor[K, V, A, O]) Next() bool {
+	return c.step(1)
+}
+
+// Prev moves the cursor to the next smaller key.
+// Returns false (and invalidates the cursor) when moving past the beginning.
+func (c *cursor[K, V, A, O]) Prev() bool {
+	return c.step(0)
+}
+
+// descend pushes the path from node to its extreme descendant in direction dir.
+func (c *cursor[K, V, A, O]) descend(node *tree[K, V, A], dir int) {
+	for ; node != nil; node = node.children[dir] {
+		c.stack = append(c.stack, node)
+	}
+}
+
+// step moves to the in-order successor (dir 1) or predecessor (dir 0).
+func (c *cursor[K, V, A, O]) step(dir int) bool {
+	if len(c.stack) == 0 {
+		return false
+	}
+	top := c.stack[len(c.stack)-1]
+	if top.children[dir] != nil {
+		c.descend(top.children[dir], 1-dir)
+		return true
+	}
+	// climb until we arrive from the opposite side
+	for {
+		child := c.stack[len(c.stack)-1]
+		c.stack = c.stack[:len(c.stack)-1]
+		if len(c.stack) == 0 {
+			return false
+		}
+		if c.stack[len(c.stack)-1].children[1-dir] == child {
+			return true
+		}
+	}
+}
+
+// CursorBuiltin is a Cursor over a NodeBuiltin.
+// It simplifies usage by handling the Builtin wrapper automatically.
+type CursorBuiltin[K BuiltinComparable, V any] struct {
+	c *Cursor[Builtin[K], V]
+}
+
+// Cursor returns a new unpositioned cursor over the map.
+func (n NodeBuiltin[K, V]) Cursor() *CursorBuiltin[K, V] {
+	return &CursorBuiltin[K, V]{n.n.Cursor()}
+}
+
+// Valid reports whether the cursor is positioned at an entry.
+func (c *CursorBuiltin[K, V]) Valid() bool {
+	return c.c.Valid()
+}
+
+// Key returns the key at the current position, or the zero value if the cursor is not valid.
+func (c *CursorBuiltin[K, V]) Key() K {
+	return c.c.Key().value
+}
+
+// Value returns the value at the current position, or the zero value if the cursor is not valid.
+func (c *CursorBuiltin[K, V]) Value() V {
+	return c.c.Value()
+}
+
+// First moves the cursor to the smallest key.
+// Returns false if the map is empty.
+func (c *CursorBuiltin[K, V]) First() bool {
+	return c.c.First()
+}
+
+// Last moves the cursor to the largest key.
+// Returns false if the map is empty.
+func (c *CursorBuiltin[K, V]) Last() bool {
+	return c.c.Last()
+}
+
+// Seek moves the cursor to the first key >= key.
+// Returns false if there is no such key.
+func (c *CursorBuiltin[K, V]) Seek(key K) bool {
+	return c.c.Seek(Builtin[K]{key})
+}
+
+// SeekBackward moves the cursor to the last key <= key.
+// Returns false if there is no such key.
+func (c *CursorBuiltin[K, V]) SeekBackward(key K) bool {
+	return c.c.SeekBackward(Builtin[K]{key})
+}
+
+// Next moves the cursor to the next larger key.
+// Returns false (and invalidates the cursor) when moving past the end.
+func (c *CursorBuiltin[K, V]) Next() bool {
+	return c.c.Next()
+}
+
+// Prev moves the cursor to the next smaller key.
+// Returns false (and invalidates the cursor) when moving past the beginning.
+func (c *CursorBuiltin[K, V]) Prev() bool {
+	return c.c.Prev()
+}
@@ -0,0 +1,123 @@
+package ordmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorEmpty(t *testing.T) {
+	var tree *Node[Builtin[int], int]
+	c := tree.Cursor()
+	require.False(t, c.Valid())
+	require.Equal(t, Builtin[int]{}, c.Key())
+	require.Equal(t, 0, c.Value())
+	require.False(t, c.First())
+	require.False(t, c.Last())
+	require.False(t, c.Seek(Builtin[int]{0}))
+	require.False(t, c.SeekBackward(Builtin[int]{0}))
+	require.False(t, c.Next())
+	require.False(t, c.Prev())
+}
+
+func TestCursor(t *testing.T) {
+	tree, model := randomTree(300, 1000)
+	entries := modelEntries(model)
+	c := tree.Cursor()
+
+	t.Run("forward", func(t *testing.T) {
+		var seen []Entry[Builtin[int], int]
+		for ok := c.First(); ok; ok = c.Next() {
+			seen = append(seen, Entry[Builtin[int], int]{c.Key(), c.Value()})
+		}
+		require.Equal(t, entries, seen)
+		require.False(t, c.Valid())
+	})
+
+	t.Run("backward", func(t *testing.T) {
+		var seen []Entry[Builtin[int], int]
+		for ok := c.Last(); ok; ok = c.Prev() {
+			seen = append([]Entry[Builtin[int], int]{{c.Key(), c.Value()}}, seen...)
+		}
+		require.Equal(t, entries, seen)
+		require.False(t, c.Valid())
+	})
+
+	t.Run("seek", func(t *testing.T) {
+		for k := -1; k <= 1001; k++ {
+			index := 0
+			for index < len(entries) && entries[index].K.value < k {
+				index++
+			}
+			if c.Seek(Builtin[int]{k}) {
+				require.Equal(t, entries[index], Entry[Builtin[int], int]{c.Key(), c.Value()})
+			} else {
+				require.Equal(t, len(entries), index)
+			}
+
+			index = len(entries) - 1
+			for index >= 0 && entries[index].K.value > k {
+				index--
+			}
+			if c.SeekBackward(Builtin[int]{k}) {
+				require.Equal(t, entries[index], Entry[Builtin[int], int]{c.Key(), c.Value()})
+			} else {
+				require.Equal(t, -1, index)
+			}
+		}
+	})
+
+	t.Run("change direction", func(t *testing.T) {
+		require.True(t, c.Seek(entries[10].K))
+		require.True(t, c.Next())
+		require.True(t, c.Next())
+		require.Equal(t, entries[12].K, c.Key())
+		require.True(t, c.Prev())
+		require.True(t, c.Prev())
+		require.True(t, c.Prev())
+		require.Equal(t, entries[9].K, c.Key())
+
+		require.True(t, c.First())
+		require.False(t, c.Prev())
+		require.True(t, c.Last())
+		require.False(t, c.Next())
+	})
+}
+
+func TestCursorAllocations(t *testing.T) {
+	tree, _ := randomTree(1000, 10000)
+	c := tree.Cursor()
+	allocs := testing.AllocsPerRun(10, func() {
+		for ok := c.First(); ok; ok = c.Next() {
+		}
+		for ok := c.Last(); ok; ok = c.Prev() {
+		}
+		c.Seek(Builtin[int]{5000})
+		c.SeekBackward(Builtin[int]{5000})
+	})
+	require.Equal(t, 0.0, allocs)
+}
+
+func TestCursorBuiltin(t *testing.T) {
+	tree, model := randomTree(300, 1000)
+	entries := unwrapEntries(modelEntries(model))
+	c := WrapBuiltin(tree).Cursor()
+	require.False(t, c.Valid())
+	require.Equal(t, 0, c.Key())
+
+	var seen []Entry[int, int]
+	for ok := c.First(); ok; ok = c.Next() {
+		seen = append(seen, Entry[int, int]{c.Key(), c.Value()})
+	}
+	require.Equal(t, entries, seen)
+	seen = nil
+	for ok := c.Last(); ok; ok = c.Prev() {
+		seen = append([]Entry[int, int]{{c.Key(), c.Value()}}, seen...)
+	}
+	require.Equal(t, entries, seen)
+
+	require.True(t, c.Seek(entries[10].K))
+	require.Equal(t, entries[10].K, c.Key())
+	require.True(t, c.SeekBackward(entries[10].K))
+	require.Equal(t, entries[10].K, c.Key())
+}
@@ -0,0 +1,229 @@
+package ordmap
+
+import "iter"
+
+// ChangeKind describes how an entry differs between two versions of a map.
+type ChangeKind uint8
+
+const (
+	// Added entries are only present in the new version.
+	Added ChangeKind = iota
+	// Removed entries are only present in the old version.
+	Removed
+	// Modified entries are present in both versions, possibly with a different value.
+	Modified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "Added"
+	case Removed:
+		return "Removed"
+	case Modified:
+		return "Modified"
+	default:
+		return "ChangeKind(?)"
+	}
+}
+
+// Change describes a single difference between two versions of a map.
+// Old is the zero value for Added entries and New is the zero value for Removed ones.
+type Change[K, V any] struct {
+	Kind ChangeKind
+	Key  K
+	Old  V
+	New  V
+}
+
+// Diff returns an iterator over the differences between two versions of a map in key order.
+// Subtrees shared by both versions are skipped without being visited, so for versions
+// derived from one another the cost is proportional to the number of changes
+// (times log N) rather than to the size of the maps.
+//
+// Keys present in both versions are reported as Modified if their values differ (by ==).
+// Use DiffFunc for values that are not comparable or need another notion of equality.
+func Diff[K Comparable[K], V comparable](old, new *Node[K, V]) iter.Seq[Change[K, V]] {
+	return DiffFunc(old, new, func(a, b V) bool { return a == b })
+}
+
+// DiffFunc is like Diff, but only reports keys present in both versions as Modified
+// when eq reports their values as different.
+// It panics if eq is nil.
+func DiffFunc[K Comparable[K], V any](old, new *Node[K, V], eq func(old, new V) bool) iter.Seq[Change[K, V]] {
+	if eq == nil {
+		panic("ordmap: DiffFunc called with a nil eq")
+	}
+	return func(yield func(Change[K, V]) bool) {
+		if old == new {
+			return
+		}
+		a, b := newFrontier(old), newFrontier(new)
+		for !a.empty() && !b.empty() {
+			x, y := a.top(), b.top()
+			if x == y {
+				// the same subtree or the same entry comes next in both versions
+				a.pop()
+				b.pop()
+				continue
+			}
+			if !x.expanded || !y.expanded {
+				// open up the taller subtree first so that shared ones line up
+				if !x.expanded && (y.expanded || x.node.h >= y.node.h) {
+					a.expand()
+				} else {
+					b.expand()
+				}
+				continue
+			}
+			ex, ey := x.node.entry, y.node.entry
+			var change Change[K, V]
+			switch {
+			case ex.K.Less(ey.K):
+				change = Change[K, V]{Kind: Removed, Key: ex.K, Old: ex.V}
+				a.pop()
+			case ey.K.Less(ex.K):
+				change = Change[K, V]{Kind: Added, Key: ey.K, New: ey.V}
+				b.pop()
+			default:
+				a.pop()
+				b.pop()
+				if eq(ex.V, ey.V) {
+					continue
+				}
+				change = Change[K, V]{Kind: Modified, Key: ey.K, Old: ex.V, New: ey.V}
+			}
+			if !yield(change) {
+				return
+			}
+		}
+		for n := a.next(); n != nil; n = a.next() {
+			if !yield(Change[K, V]{Kind: Removed, Key: n.entry.K, Old: n.entry.V}) {
+				return
+			}
+		}
+		for n := b.next(); n != nil; n = b.next() {
+			if !yield(Change[K, V]{Kind: Added, Key: n.entry.K, New: n.entry.V}) {
+				return
+			}
+		}
+	}
+}
+
+// DiffBuiltin returns an iterator over the differences between two versions of a map.
+// See Diff for details.
+func DiffBuiltin[K BuiltinComparable, V comparable](old, new NodeBuiltin[K, V]) iter.Seq[Change[K, V]] {
+	return unwrapChanges(Diff(old.n, new.n))
+}
+
+// DiffFuncBuiltin returns an iterator over the differences between two versions of a map.
+// See DiffFunc for details.
+func DiffFuncBuiltin[K BuiltinComparable, V any](old, new NodeBuiltin[K, V], eq func(old, new V) bool) iter.Seq[Change[K, V]] {
+	return unwrapChanges(DiffFunc(old.n, new.n, eq))
+}
+
+// Equal reports whether a and b contain the same keys with values considered equal by eq.
+// Maps of different sizes are rejected right away and subtrees shared by both maps
+// are skipped, so comparing successive versions of a map is cheap.
+// It panics if eq is nil, even for maps it could tell apart without it.
+func Equal[K Comparable[K], V any](a, b *Node[K, V], eq func(a, b V) bool) bool {
+	if eq == nil {
+		panic("ordmap: Equal called with a nil eq")
+	}
+	if a == b {
+		return true
+	}
+	if a.Len() != b.Len() {
+		return false
+	}
+	for range DiffFunc(a, b, eq) {
+		return false
+	}
+	return true
+}
+
+// EqualKeys reports whether a and b contain the same keys, ignoring values.
+// See Equal for details.
+func EqualKeys[K Comparable[K], V any](a, b *Node[K, V]) bool {
+	return Equal(a, b, func(V, V) bool { return true })
+}
+
+// EqualBuiltin reports whether a and b contain the same keys with values considered equal by eq.
+// See Equal for details.
+func EqualBuiltin[K BuiltinComparable, V any](a, b NodeBuiltin[K, V], eq func(a, b V) bool) bool {
+	return Equal(a.n, b.n, eq)
+}
+
+// EqualKeysBuiltin reports whether a and b contain the same keys, ignoring values.
+// See Equal for details.
+func EqualKeysBuiltin[K BuiltinComparable, V any](a, b NodeBuiltin[K, V]) bool {
+	return EqualKeys(a.n, b.n)
+}
+
+func unwrapChanges[K BuiltinComparable, V any](changes iter.Seq[Change[Builtin[K], V]]) iter.Seq[Change[K, V]] {
+	return func(yield func(Change[K, V]) bool) {
+		for c := range changes {
+			if !yield(Change[K, V]{c.Kind, c.Key.value, c.Old, c.New}) {
+				return
+			}
+		}
+	}
+}
+
+// frontierItem is either a whole subtree or, once expanded, just the entry of its root.
+type frontierItem[K Comparable[K], V any] struct {
+	node     *Node[K, V]
+	expanded bool
+}
+
+// frontier holds the part of a tree that has not been visited yet, in key order
+// from the top of the stack down.
+type frontier[K Comparable[K], V any] struct {
+	stack []frontierItem[K, V]
+}
+
+func newFrontier[K Comparable[K], V any](node *Node[K, V]) *frontier[K, V] {
+	// expanding pops one item and pushes at most three, once per level
+	f := &frontier[K, V]{stack: make([]frontierItem[K, V], 0, 2*node.height()+1)}
+	f.push(node)
+	return f
+}
+
+func (f *frontier[K, V]) push(node *Node[K, V]) {
+	if node != nil {
+		f.stack = append(f.stack, frontierItem[K, V]{node: node})
+	}
+}
+
+func (f *frontier[K, V]) empty() bool {
+	return len(f.stack) == 0
+}
+
+func (f *frontier[K, V]) top() frontierItem[K, V] {
+	return f.stack[len(f.stack)-1]
+}
+
+func (f *frontier[K, V]) pop() {
+	f.stack = f.stack[:len(f.stack)-1]
+}
+
+// expand replaces the subtree on top with its left subtree, its root entry and its right subtree.
+func (f *frontier[K, V]) expand() {
+	node := f.top().node
+	f.pop()
+	f.push(node.child(1))
+	f.stack = append(f.stack, frontierItem[K, V]{node: node, expanded: true})
+	f.push(node.child(0))
+}
+
+// next removes and returns the node holding the next entry, or nil if there are none left.
+func (f *frontier[K, V]) next() *Node[K, V] {
+	for !f.empty() {
+		if item := f.top(); item.expanded {
+			f.pop()
+			return item.node
+		}
+		f.expand()
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package ordmap
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// modelDiff computes the expected changes between two models by brute force.
+func modelDiff(old, new map[int]int) []Change[int, int] {
+	var changes []Change[int, int]
+	for k, v := range old {
+		if nv, ok := new[k]; !ok {
+			changes = append(changes, Change[int, int]{Kind: Removed, Key: k, Old: v})
+		} else if nv != v {
+			changes = append(changes, Change[int, int]{Kind: Modified, Key: k, Old: v, New: nv})
+		}
+	}
+	for k, v := range new {
+		if _, ok := old[k]; !ok {
+			changes = append(changes, Change[int, int]{Kind: Added, Key: k, New: v})
+		}
+	}
+	slices.SortFunc(changes, func(a, b Change[int, int]) int { return a.Key - b.Key })
+	return changes
+}
+
+func TestDiff(t *testing.T) {
+	intEq := func(a, b int) bool { return a == b }
+	for _, edits := range []int{0, 1, 5, 50, 500} {
+		old, oldModel := randomTree(1000, 2000)
+		new := old
+		newModel := make(map[int]int, len(oldModel))
+		for k, v := range oldModel {
+			newModel[k] = v
+		}
+		for range edits {
+			k := rand.Intn(2000)
+			if rand.Intn(3) == 0 {
+				new = new.Remove(Builtin[int]{k})
+				delete(newModel, k)
+			} else {
+				v := rand.Intn(3) // often the same value as before
+				new = new.Insert(Builtin[int]{k}, v)
+				newModel[k] = v
+			}
+		}
+		expected := modelDiff(oldModel, newModel)
+
+		var exact []Change[int, int]
+		for c := range DiffFunc(old, new, intEq) {
+			exact = append(exact, Change[int, int]{c.Kind, c.Key.value, c.Old, c.New})
+		}
+		require.Equal(t, expected, exact, "%v edits", edits)
+		require.Equal(t, expected, slices.Collect(DiffFuncBuiltin(WrapBuiltin(old), WrapBuiltin(new), intEq)))
+
+		require.Equal(t, expected, slices.Collect(DiffBuiltin(WrapBuiltin(old), WrapBuiltin(new))))
+
+		// values are only compared around the edits
+		compared := 0
+		for range DiffFunc(old, new, func(a, b int) bool { compared++; return a == b }) {
+		}
+		require.LessOrEqual(t, compared, edits*2*new.height())
+
+		// reversing the direction swaps additions and removals
+		var reversed []Change[int, int]
+		for c := range DiffFunc(new, old, intEq) {
+			kind := map[ChangeKind]ChangeKind{Added: Removed, Removed: Added, Modified: Modified}[c.Kind]
+			reversed = append(reversed, Change[int, int]{kind, c.Key.value, c.New, c.Old})
+		}
+		require.Equal(t, expected, reversed)
+	}
+}
+
+func TestDiffEdgeCases(t *testing.T) {
+	tree, model := randomTree(100, 200)
+	require.Empty(t, slices.Collect(Diff(tree, tree)))
+
+	var empty *Node[Builtin[int], int]
+	require.Equal(t, modelDiff(nil, model), slices.Collect(unwrapChanges(Diff(empty, tree))))
+	require.Equal(t, modelDiff(model, nil), slices.Collect(unwrapChanges(Diff(tree, empty))))
+
+	// unrelated trees with equal contents share nothing but have no differences
+	rebuilt := FromSorted(tree.Entries())
+	require.Empty(t, slices.Collect(DiffFunc(tree, rebuilt, func(a, b int) bool { return a == b })))
+	require.Empty(t, slices.Collect(Diff(tree, rebuilt)))
+	never := func(a, b int) bool { return false }
+	require.Len(t, slices.Collect(DiffFunc(tree, rebuilt, never)), tree.Len())
+	require.PanicsWithValue(t, "ordmap: DiffFunc called with a nil eq", func() { DiffFunc(tree, rebuilt, nil) })
+
+	for range Diff(empty, tree) {
+		break // early termination
+	}
+	require.Equal(t, "Modified", Modified.String())
+}
+
+func TestEqual(t *testing.T) {
+	intEq := func(a, b int) bool { return a == b }
+	tree, model := randomTree(300, 1000)
+	require.True(t, Equal(tree, tree, intEq))
+	require.True(t, Equal(tree, FromSorted(tree.Entries()), intEq))
+
+	var empty *Node[Builtin[int], int]
+	require.True(t, Equal(empty, empty, intEq))
+	require.False(t, Equal(empty, tree, intEq))
+	require.False(t, Equal(tree, empty, intEq))
+
+	for k, v := range model {
+		changed := tree.Insert(Builtin[int]{k}, v+1)
+		require.False(t, Equal(tree, changed, intEq))
+		require.True(t, EqualKeys(tree, changed))
+		require.True(t, Equal(tree, changed.Insert(Builtin[int]{k}, v), intEq))
+
+		// same size, different keys
+		moved := tree.Remove(Builtin[int]{k}).Insert(Builtin[int]{-1}, v)
+		require.False(t, EqualKeys(tree, moved))
+		require.False(t, EqualKeys(moved, tree))
+	}
+	require.False(t, EqualKeys(tree, tree.DeleteMax()))
+
+	a := FromSortedBuiltin([]Entry[int, int]{{1, 1}, {2, 2}})
+	require.True(t, EqualBuiltin(a, NewBuiltin[int, int]().Insert(2, 2).Insert(1, 1), intEq))
+	require.False(t, EqualBuiltin(a, a.Insert(2, 3), intEq))
+	require.True(t, EqualKeysBuiltin(a, a.Insert(2, 3)))
+
+	require.PanicsWithValue(t, "ordmap: Equal called with a nil eq", func() { Equal(tree, tree, nil) })
+}
+
+func BenchmarkDiff(b *testing.B) {
+	var tree *Node[Builtin[int], int]
+	for i := range 100000 {
+		tree = tree.Insert(Builtin[int]{i}, i)
+	}
+	changed := tree.Insert(Builtin[int]{500}, -1).Remove(Builtin[int]{90000})
+	modified := tree.Insert(Builtin[int]{500}, -1) // same size, so Equal has to look
+	b.Run("Diff", func(b *testing.B) {
+		for range b.N {
+			for range Diff(tree, changed) {
+			}
+		}
+	})
+	b.Run("Equal", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			Equal(tree, modified, func(a, b int) bool { return a == b })
+		}
+	})
+	b.Run("full scan", func(b *testing.B) {
+		// lower bound for diffing by merge-iterating both versions
+		for range b.N {
+			for range tree.All() {
+			}
+			for range changed.All() {
+			}
+		}
+	})
+}
@@ -43,4 +43,11 @@ func main() {
 		}
 		fmt.Println(k, v)
 	}
+
+	// or let the map do the bounds checking for you
+	// e.g. all preferences for user 1 strictly below 3
+	lo, hi := CompositeKey{1, 0}, CompositeKey{1, 3}
+	for k, v := range preferences.Range(lo, hi, ordmap.RangeOptions{Hi: ordmap.Exclusive}) {
+		fmt.Println(k, v)
+	}
 }
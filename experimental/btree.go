@@ -3,6 +3,7 @@ package ordmap
 import (
 	"fmt"
 	"iter"
+	"slices"
 )
 
 const MAX = 5 // must be odd
@@ -16,6 +17,49 @@ type Entry[K, V any] struct {
 	V V
 }
 
+// Bound describes how one end of a range is treated.
+// Like Comparable and Entry it mirrors the type of the same name in the root package,
+// so that this package stays self-contained and does not import it.
+type Bound uint8
+
+const (
+	// Inclusive bounds include the boundary key itself.
+	Inclusive Bound = iota
+	// Exclusive bounds exclude the boundary key.
+	Exclusive
+	// Unbounded ends ignore the boundary key altogether.
+	Unbounded
+)
+
+// RangeOptions configures both ends of a range.
+// The zero value describes the closed interval [lo, hi].
+type RangeOptions struct {
+	Lo Bound
+	Hi Bound
+}
+
+func aboveLower[K Comparable[K]](k, lo K, bound Bound) bool {
+	switch bound {
+	case Unbounded:
+		return true
+	case Exclusive:
+		return lo.Less(k)
+	default:
+		return !k.Less(lo)
+	}
+}
+
+func belowUpper[K Comparable[K]](k, hi K, bound Bound) bool {
+	switch bound {
+	case Unbounded:
+		return true
+	case Exclusive:
+		return k.Less(hi)
+	default:
+		return !hi.Less(k)
+	}
+}
+
 func New[K Comparable[K], V any]() *OrdMap[K, V] {
 	return nil
 }
@@ -118,6 +162,81 @@ func (n *OrdMap[K, V]) Remove(key K) *OrdMap[K, V] {
 	return n
 }
 
+// Update inserts, modifies or deletes the entry for key in a single descent.
+// fn receives the current value (or the zero value) and whether the key exists
+// and returns the new value and whether to keep the entry.
+// Insertion and removal restructure the tree top-down (splitting full nodes or
+// filling minimal ones ahead of the descent), and we only learn which one we are
+// doing once we reach the key. So before entering a child we make sure it is
+// neither full nor minimal, which leaves room for both.
+// If the key does not exist and fn does not keep it, the map is returned unchanged.
+func (n *OrdMap[K, V]) Update(key K, fn func(old V, exists bool) (V, bool)) *OrdMap[K, V] {
+	if n == nil {
+		var zero V
+		if value, keep := fn(zero, false); keep {
+			return n.Insert(key, value)
+		}
+		return n
+	}
+	original := n
+	if n.order == MAX { // full root, need to split
+		left, entry, right := n.split()
+		var entries [MAX]Entry[K, V]
+		entries[0] = entry
+		var subtrees [MAX + 1]*OrdMap[K, V]
+		subtrees[0] = left
+		subtrees[1] = right
+		n = mkOrdMap(1, entries, subtrees)
+	} else {
+		n = n.dup()
+	}
+	root := n
+OUTER:
+	for {
+		index := int(n.order)
+		for i := 0; i < int(n.order); i++ {
+			if key.Less(n.entries[i].K) {
+				index = i
+				break
+			}
+			if !n.entries[i].K.Less(key) { // found
+				value, keep := fn(n.entries[i].V, true)
+				if keep {
+					n.entries[i].V = value
+				} else {
+					n.removeStepMut(key) // n is not minimal unless it is the root
+					if root.order == 0 {
+						return nil
+					}
+				}
+				return root
+			}
+		}
+		if n.height == 1 {
+			var zero V
+			value, keep := fn(zero, false)
+			if !keep {
+				return original
+			}
+			n.insertNonFullMut(key, value)
+			return root
+		}
+		switch n.subtrees[index].order {
+		case MAX:
+			n.splitChildMut(index)
+			continue OUTER // the middle entry moved up here and may be the key
+		case 1:
+			_ = n.ensureChildNotMinimal(index)
+			if n.order == 0 { // degenerated, need to drop a level
+				*n = *n.subtrees[0]
+			}
+			continue OUTER // entries may have moved, look again
+		}
+		n.subtrees[index] = n.subtrees[index].dup()
+		n = n.subtrees[index]
+	}
+}
+
 func (n *OrdMap[K, V]) Min() *Entry[K, V] {
 	if n == nil {
 		return nil
@@ -142,6 +261,54 @@ func (n *OrdMap[K, V]) Max() *Entry[K, V] {
 	}
 }
 
+func (n *OrdMap[K, V]) PopMin() (*Entry[K, V], *OrdMap[K, V]) {
+	if n == nil {
+		return nil, nil
+	}
+	if n.height == 1 && n.order == 1 {
+		entry := n.entries[0]
+		return &entry, nil
+	}
+	n = n.dup()
+	if n.height > 1 {
+		_ = n.ensureChildNotMinimal(0)
+		if n.order == 0 { // degenerated, need to drop a level
+			*n = *n.subtrees[0]
+		}
+	}
+	entry := n.popMinMut()
+	return &entry, n
+}
+
+func (n *OrdMap[K, V]) PopMax() (*Entry[K, V], *OrdMap[K, V]) {
+	if n == nil {
+		return nil, nil
+	}
+	if n.height == 1 && n.order == 1 {
+		entry := n.entries[0]
+		return &entry, nil
+	}
+	n = n.dup()
+	if n.height > 1 {
+		_ = n.ensureChildNotMinimal(int(n.order))
+		if n.order == 0 { // degenerated, need to drop a level
+			*n = *n.subtrees[0]
+		}
+	}
+	entry := n.popMaxMut()
+	return &entry, n
+}
+
+func (n *OrdMap[K, V]) DeleteMin() *OrdMap[K, V] {
+	_, rest := n.PopMin()
+	return rest
+}
+
+func (n *OrdMap[K, V]) DeleteMax() *OrdMap[K, V] {
+	_, rest := n.PopMax()
+	return rest
+}
+
 func (n *OrdMap[K, V]) Height() int {
 	if n == nil {
 		return 0
@@ -387,6 +554,259 @@ func (n *OrdMap[K, V]) BackwardFrom(k K) iter.Seq2[K, V] {
 	}
 }
 
+func (n *OrdMap[K, V]) Range(lo, hi K, opts RangeOptions) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		// checkLo/checkHi track whether the bound still needs to be compared
+		// against in this subtree.
+		var step func(n *OrdMap[K, V], checkLo, checkHi bool) bool
+		step = func(n *OrdMap[K, V], checkLo, checkHi bool) bool {
+			if n == nil {
+				return true
+			}
+			for i := 0; i < int(n.order); i++ {
+				entry := n.entries[i]
+				okLo := !checkLo || aboveLower(entry.K, lo, opts.Lo)
+				okHi := !checkHi || belowUpper(entry.K, hi, opts.Hi)
+
+				// subtrees[i] holds keys below entry.K, so it is only
+				// worth visiting if entry.K is not below lo.
+				if okLo {
+					if !step(n.subtrees[i], checkLo, !okHi) {
+						return false
+					}
+				}
+				// Everything to the right of an entry above hi is above hi too.
+				if !okHi {
+					return true
+				}
+				if okLo {
+					if !yield(entry.K, entry.V) {
+						return false
+					}
+					checkLo = false
+				}
+			}
+			return step(n.subtrees[n.order], checkLo, checkHi)
+		}
+		step(n, true, true)
+	}
+}
+
+func (n *OrdMap[K, V]) BackwardRange(lo, hi K, opts RangeOptions) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		// Mirror image of Range: walks entries from last to first.
+		var step func(n *OrdMap[K, V], checkLo, checkHi bool) bool
+		step = func(n *OrdMap[K, V], checkLo, checkHi bool) bool {
+			if n == nil {
+				return true
+			}
+			for i := int(n.order) - 1; i >= 0; i-- {
+				entry := n.entries[i]
+				okLo := !checkLo || aboveLower(entry.K, lo, opts.Lo)
+				okHi := !checkHi || belowUpper(entry.K, hi, opts.Hi)
+
+				// subtrees[i+1] holds keys above entry.K, so it is only
+				// worth visiting if entry.K is not above hi.
+				if okHi {
+					if !step(n.subtrees[i+1], !okLo, checkHi) {
+						return false
+					}
+				}
+				// Everything to the left of an entry below lo is below lo too.
+				if !okLo {
+					return true
+				}
+				if okHi {
+					if !yield(entry.K, entry.V) {
+						return false
+					}
+					checkHi = false
+				}
+			}
+			return step(n.subtrees[0], checkLo, checkHi)
+		}
+		step(n, true, true)
+	}
+}
+
+type cursorFrame[K Comparable[K], V any] struct {
+	node  *OrdMap[K, V]
+	index int // entry index in the top frame, index of the subtree descended into in the others
+}
+
+// Cursor is a bidirectional iterator that does not allocate once created.
+// A new cursor is not positioned; call First, Last, Seek or SeekBackward before reading.
+type Cursor[K Comparable[K], V any] struct {
+	root  *OrdMap[K, V]
+	stack []cursorFrame[K, V]
+}
+
+func (n *OrdMap[K, V]) Cursor() *Cursor[K, V] {
+	return &Cursor[K, V]{
+		root:  n,
+		stack: make([]cursorFrame[K, V], 0, n.Height()),
+	}
+}
+
+func (c *Cursor[K, V]) Valid() bool {
+	return len(c.stack) > 0
+}
+
+func (c *Cursor[K, V]) Key() (key K) {
+	if len(c.stack) == 0 {
+		return
+	}
+	top := c.stack[len(c.stack)-1]
+	return top.node.entries[top.index].K
+}
+
+func (c *Cursor[K, V]) Value() (value V) {
+	if len(c.stack) == 0 {
+		return
+	}
+	top := c.stack[len(c.stack)-1]
+	return top.node.entries[top.index].V
+}
+
+func (c *Cursor[K, V]) First() bool {
+	c.stack = c.stack[:0]
+	c.descendFirst(c.root)
+	return c.Valid()
+}
+
+func (c *Cursor[K, V]) Last() bool {
+	c.stack = c.stack[:0]
+	c.descendLast(c.root)
+	return c.Valid()
+}
+
+// Seek moves the cursor to the first key >= key.
+func (c *Cursor[K, V]) Seek(key K) bool {
+	c.stack = c.stack[:0]
+	for n := c.root; n != nil; {
+		i := 0
+		for i < int(n.order) && n.entries[i].K.Less(key) {
+			i++
+		}
+		c.stack = append(c.stack, cursorFrame[K, V]{n, i})
+		if i < int(n.order) && !key.Less(n.entries[i].K) {
+			return true // exact match
+		}
+		if n.height == 1 {
+			if i < int(n.order) {
+				return true
+			}
+			return c.ascendForward()
+		}
+		n = n.subtrees[i]
+	}
+	return false
+}
+
+// SeekBackward moves the cursor to the last key <= key.
+func (c *Cursor[K, V]) SeekBackward(key K) bool {
+	c.stack = c.stack[:0]
+	for n := c.root; n != nil; {
+		i := int(n.order) - 1
+		for i >= 0 && key.Less(n.entries[i].K) {
+			i--
+		}
+		if i >= 0 && !n.entries[i].K.Less(key) {
+			c.stack = append(c.stack, cursorFrame[K, V]{n, i})
+			return true // exact match
+		}
+		if n.height == 1 {
+			c.stack = append(c.stack, cursorFrame[K, V]{n, i})
+			if i >= 0 {
+				return true
+			}
+			return c.ascendBackward()
+		}
+		c.stack = append(c.stack, cursorFrame[K, V]{n, i + 1})
+		n = n.subtrees[i+1]
+	}
+	return false
+}
+
+func (c *Cursor[K, V]) Next() bool {
+	if len(c.stack) == 0 {
+		return false
+	}
+	top := &c.stack[len(c.stack)-1]
+	if top.node.height > 1 {
+		top.index += 1
+		c.descendFirst(top.node.subtrees[top.index])
+		return true
+	}
+	if top.index+1 < int(top.node.order) {
+		top.index += 1
+		return true
+	}
+	return c.ascendForward()
+}
+
+func (c *Cursor[K, V]) Prev() bool {
+	if len(c.stack) == 0 {
+		return false
+	}
+	top := &c.stack[len(c.stack)-1]
+	if top.node.height > 1 {
+		c.descendLast(top.node.subtrees[top.index])
+		return true
+	}
+	if top.index > 0 {
+		top.index -= 1
+		return true
+	}
+	return c.ascendBackward()
+}
+
+func (c *Cursor[K, V]) descendFirst(n *OrdMap[K, V]) {
+	for ; n != nil; n = n.subtrees[0] {
+		c.stack = append(c.stack, cursorFrame[K, V]{n, 0})
+	}
+}
+
+func (c *Cursor[K, V]) descendLast(n *OrdMap[K, V]) {
+	for n != nil {
+		if n.height == 1 {
+			c.stack = append(c.stack, cursorFrame[K, V]{n, int(n.order) - 1})
+			return
+		}
+		c.stack = append(c.stack, cursorFrame[K, V]{n, int(n.order)})
+		n = n.subtrees[n.order]
+	}
+}
+
+// ascendForward pops exhausted frames until reaching an ancestor with an entry
+// right after the subtree we came from.
+func (c *Cursor[K, V]) ascendForward() bool {
+	c.stack = c.stack[:len(c.stack)-1]
+	for len(c.stack) > 0 {
+		top := c.stack[len(c.stack)-1]
+		if top.index < int(top.node.order) {
+			return true
+		}
+		c.stack = c.stack[:len(c.stack)-1]
+	}
+	return false
+}
+
+// ascendBackward pops exhausted frames until reaching an ancestor with an entry
+// right before the subtree we came from.
+func (c *Cursor[K, V]) ascendBackward() bool {
+	c.stack = c.stack[:len(c.stack)-1]
+	for len(c.stack) > 0 {
+		top := &c.stack[len(c.stack)-1]
+		if top.index > 0 {
+			top.index -= 1
+			return true
+		}
+		c.stack = c.stack[:len(c.stack)-1]
+	}
+	return false
+}
+
 func (n *OrdMap[K, V]) removeStepMut(key K) {
 OUTER:
 	for {
@@ -467,22 +887,13 @@ OUTER:
 		}
 		child := n.subtrees[index]
 		if child.order == MAX { // full, need to split before entering
-			left, entry, right := child.split()
-			for i := int(n.order); i > index; i-- {
-				n.entries[i] = n.entries[i-1]
-			}
-			n.entries[index] = entry
-			for i := int(n.order); i > index; i-- {
-				n.subtrees[i+1] = n.subtrees[i]
-			}
-			n.subtrees[index] = left
-			n.subtrees[index+1] = right
-			n.order += 1
+			n.splitChildMut(index)
+			entry := n.entries[index]
 			if key.Less(entry.K) {
-				n = left
+				n = n.subtrees[index]
 				continue OUTER
 			} else if entry.K.Less(key) {
-				n = right
+				n = n.subtrees[index+1]
 				continue OUTER
 			} else {
 				n.entries[index].V = value
@@ -496,6 +907,21 @@ OUTER:
 	}
 }
 
+// splitChildMut splits the full child at index in two, moving its middle entry up into n.
+func (n *OrdMap[K, V]) splitChildMut(index int) {
+	left, entry, right := n.subtrees[index].split()
+	for i := int(n.order); i > index; i-- {
+		n.entries[i] = n.entries[i-1]
+	}
+	n.entries[index] = entry
+	for i := int(n.order); i > index; i-- {
+		n.subtrees[i+1] = n.subtrees[i]
+	}
+	n.subtrees[index] = left
+	n.subtrees[index+1] = right
+	n.order += 1
+}
+
 func (n *OrdMap[K, V]) ensureChildNotMinimal(index int) int {
 	if n.subtrees[index].order > 1 {
 		return index
@@ -613,6 +1039,22 @@ OUTER:
 	}
 }
 
+func (n *OrdMap[K, V]) popMaxMut() Entry[K, V] {
+OUTER:
+	for {
+		if n.height == 1 {
+			n.order -= 1
+			e := n.entries[n.order]
+			n.entries[n.order] = Entry[K, V]{}
+			return e
+		}
+		index := n.ensureChildNotMinimal(int(n.order))
+		n.subtrees[index] = n.subtrees[index].dup()
+		n = n.subtrees[index]
+		continue OUTER
+	}
+}
+
 func (n OrdMap[K, V]) dup() *OrdMap[K, V] {
 	return &n
 }
@@ -628,3 +1070,47 @@ func (n *OrdMap[K, V]) visual() string {
 	s += " ]"
 	return s
 }
+
+func (n *OrdMap[K, V]) Keys() iter.Seq[K] {
+	return keysOf(n.All())
+}
+
+func (n *OrdMap[K, V]) Values() iter.Seq[V] {
+	return valuesOf(n.All())
+}
+
+func (n *OrdMap[K, V]) KeysSlice() []K {
+	return slices.AppendSeq(make([]K, 0, n.Len()), n.Keys())
+}
+
+func (n *OrdMap[K, V]) ValuesSlice() []V {
+	return slices.AppendSeq(make([]V, 0, n.Len()), n.Values())
+}
+
+func (n *OrdMap[K, V]) KeysRange(lo, hi K, opts RangeOptions) iter.Seq[K] {
+	return keysOf(n.Range(lo, hi, opts))
+}
+
+func (n *OrdMap[K, V]) ValuesRange(lo, hi K, opts RangeOptions) iter.Seq[V] {
+	return valuesOf(n.Range(lo, hi, opts))
+}
+
+func keysOf[K, V any](seq iter.Seq2[K, V]) iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k := range seq {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+func valuesOf[K, V any](seq iter.Seq2[K, V]) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, v := range seq {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
@@ -2,7 +2,11 @@ package ordmap
 
 import (
 	"fmt"
+	"go/parser"
+	"go/token"
+	"maps"
 	"math/rand"
+	"slices"
 	"sort"
 	"testing"
 
@@ -21,6 +25,15 @@ func intKey(i int) *myKey {
 	return &k
 }
 
+func TestSelfContained(t *testing.T) {
+	// Bound, RangeOptions, Comparable and Entry are mirrored here rather than imported.
+	file, err := parser.ParseFile(token.NewFileSet(), "btree.go", nil, parser.ImportsOnly)
+	require.NoError(t, err)
+	for _, spec := range file.Imports {
+		require.NotContains(t, spec.Path.Value, "go-ordmap")
+	}
+}
+
 func TestIntKey(t *testing.T) {
 	require.False(t, intKey(1).Less(intKey(1)))
 	require.True(t, intKey(1).Less(intKey(2)))
@@ -551,3 +564,297 @@ func TestBackwardFrom(t *testing.T) {
 		}
 	})
 }
+
+func TestRange(t *testing.T) {
+	sizes := []int{0, 1, 2, 5, 7, 13, 30, 100}
+	bounds := []Bound{Inclusive, Exclusive, Unbounded}
+	for _, N := range sizes {
+		t.Run(fmt.Sprintf("size_%03d", N), func(t *testing.T) {
+			m := NewModel(t)
+			for i := 0; i < N; i++ {
+				m.Insert(intKey(i*2), i*2) // even keys only
+			}
+			for _, loBound := range bounds {
+				for _, hiBound := range bounds {
+					opts := RangeOptions{Lo: loBound, Hi: hiBound}
+					for lo := -1; lo <= N*2; lo += 3 {
+						for hi := lo - 1; hi <= N*2+1; hi += 3 {
+							expected := []Entry[*myKey, int]{}
+							for _, e := range m.entries {
+								if aboveLower(e.K, intKey(lo), loBound) && belowUpper(e.K, intKey(hi), hiBound) {
+									expected = append(expected, e)
+								}
+							}
+
+							entries := []Entry[*myKey, int]{}
+							for k, v := range m.tree.Range(intKey(lo), intKey(hi), opts) {
+								entries = append(entries, Entry[*myKey, int]{k, v})
+							}
+							require.Equal(t, expected, entries, "%v [%v, %v]", opts, lo, hi)
+
+							entries = []Entry[*myKey, int]{}
+							for k, v := range m.tree.BackwardRange(intKey(lo), intKey(hi), opts) {
+								entries = append([]Entry[*myKey, int]{{k, v}}, entries...)
+							}
+							require.Equal(t, expected, entries, "%v [%v, %v]", opts, lo, hi)
+						}
+					}
+				}
+			}
+		})
+	}
+
+	t.Run("early_termination", func(t *testing.T) {
+		m := NewModel(t)
+		for i := 0; i < 100; i++ {
+			m.Insert(intKey(i), i)
+		}
+		values := []int{}
+		for _, v := range m.tree.Range(intKey(10), intKey(90), RangeOptions{}) {
+			values = append(values, v)
+			if len(values) == 3 {
+				break
+			}
+		}
+		require.Equal(t, []int{10, 11, 12}, values)
+		values = []int{}
+		for _, v := range m.tree.BackwardRange(intKey(10), intKey(90), RangeOptions{}) {
+			values = append(values, v)
+			if len(values) == 3 {
+				break
+			}
+		}
+		require.Equal(t, []int{90, 89, 88}, values)
+	})
+}
+
+func TestKeysValues(t *testing.T) {
+	m := NewModel(t)
+	for _, i := range rand.Perm(100) {
+		m.Insert(intKey(i), i*10)
+	}
+	var keys []*myKey
+	var values []int
+	for _, e := range m.entries {
+		keys = append(keys, e.K)
+		values = append(values, e.V)
+	}
+	require.Equal(t, keys, m.tree.KeysSlice())
+	require.Equal(t, values, m.tree.ValuesSlice())
+	require.Equal(t, keys, slices.Collect(m.tree.Keys()))
+	require.Equal(t, values, slices.Collect(m.tree.Values()))
+	require.Equal(t, keys[20:40], slices.Collect(m.tree.KeysRange(intKey(20), intKey(40), RangeOptions{Hi: Exclusive})))
+	require.Equal(t, values[21:41], slices.Collect(m.tree.ValuesRange(intKey(20), intKey(40), RangeOptions{Lo: Exclusive})))
+}
+
+func TestUpdate(t *testing.T) {
+	m := NewModel(t)
+	increment := func(old int, exists bool) (int, bool) {
+		return old + 1, true
+	}
+	dropEven := func(old int, exists bool) (int, bool) {
+		return old, old%2 != 0
+	}
+	calls := 0
+	once := func(fn func(int, bool) (int, bool)) func(int, bool) (int, bool) {
+		return func(old int, exists bool) (int, bool) {
+			calls++
+			return fn(old, exists)
+		}
+	}
+	N := 100
+	for i := 0; i < N*3; i++ {
+		k := m.r.Intn(N)
+		old, exists := m.tree.Get(intKey(k))
+		before, entries := m.tree, m.tree.Entries()
+		calls = 0
+		if m.r.Float64() < 0.7 {
+			m.tree = m.tree.Update(intKey(k), once(increment))
+			m.insertEntry(intKey(k), old+1)
+		} else {
+			m.tree = m.tree.Update(intKey(k), once(dropEven))
+			if !exists {
+				require.Same(t, before, m.tree)
+			}
+			if old%2 == 0 {
+				m.deleteEntry(intKey(k))
+			}
+		}
+		require.Equal(t, 1, calls)
+		require.Equal(t, entries, before.Entries()) // persistent
+		m.checkInvariants()
+	}
+}
+
+func TestUpdatePersistence(t *testing.T) {
+	// Update splits and merges nodes on the way down before it knows whether the key
+	// exists, so check that no older version is ever touched by a later update.
+	r := rand.New(rand.NewSource(1))
+	N := 2000
+	snapshot := func(tree *OrdMap[*myKey, int]) []int {
+		var flat []int
+		for k, v := range tree.All() {
+			flat = append(flat, int(*k), v)
+		}
+		return flat
+	}
+	type version struct {
+		tree *OrdMap[*myKey, int]
+		flat []int
+	}
+	var versions []version
+	model := map[int]int{}
+	var tree *OrdMap[*myKey, int]
+	for i := 0; i < N*10; i++ {
+		k := r.Intn(N)
+		// skewed towards inserting until the map is about half full, then balanced churn
+		keep := r.Intn(N) >= len(model)
+		tree = tree.Update(intKey(k), func(old int, exists bool) (int, bool) {
+			return old + 1, keep
+		})
+		if keep {
+			model[k]++
+		} else {
+			delete(model, k)
+		}
+		if i%50 == 0 {
+			versions = append(versions, version{tree, snapshot(tree)})
+		}
+		if i%1000 == 999 {
+			for _, v := range versions {
+				require.Equal(t, v.flat, snapshot(v.tree))
+			}
+		}
+	}
+	var expected []int
+	for _, k := range slices.Sorted(maps.Keys(model)) {
+		expected = append(expected, k, model[k])
+	}
+	require.Equal(t, expected, snapshot(tree))
+}
+
+func TestPop(t *testing.T) {
+	var empty *OrdMap[*myKey, int]
+	e, rest := empty.PopMin()
+	require.Nil(t, e)
+	require.Nil(t, rest)
+	e, rest = empty.PopMax()
+	require.Nil(t, e)
+	require.Nil(t, rest)
+
+	sizes := []int{1, 2, 3, 5, 7, 13, 30, 100}
+	for _, N := range sizes {
+		t.Run(fmt.Sprintf("min_%03d", N), func(t *testing.T) {
+			m := NewModel(t)
+			for i := 0; i < N; i++ {
+				m.Insert(intKey(m.r.Intn(N*2)), i)
+			}
+			for len(m.entries) > 0 {
+				oldTree := m.tree
+				oldEntries := oldTree.Entries()
+				var e *Entry[*myKey, int]
+				e, m.tree = m.tree.PopMin()
+				require.Equal(t, m.entries[0], *e)
+				m.entries = m.entries[1:]
+				m.checkInvariants()
+				require.Equal(t, oldEntries, oldTree.Entries()) // persistence check
+			}
+			require.Nil(t, m.tree)
+		})
+		t.Run(fmt.Sprintf("max_%03d", N), func(t *testing.T) {
+			m := NewModel(t)
+			for i := 0; i < N; i++ {
+				m.Insert(intKey(m.r.Intn(N*2)), i)
+			}
+			for len(m.entries) > 0 {
+				oldTree := m.tree
+				oldEntries := oldTree.Entries()
+				m.tree = m.tree.DeleteMax()
+				m.entries = m.entries[:len(m.entries)-1]
+				m.checkInvariants()
+				require.Equal(t, oldEntries, oldTree.Entries()) // persistence check
+			}
+			require.Nil(t, m.tree)
+		})
+	}
+
+	m := NewModel(t)
+	for i := 0; i < 10; i++ {
+		m.Insert(intKey(i), i)
+	}
+	m.tree = m.tree.DeleteMin()
+	m.entries = m.entries[1:]
+	m.checkInvariants()
+	e, _ = m.tree.PopMax()
+	require.Equal(t, 9, e.V)
+}
+
+func TestCursor(t *testing.T) {
+	var empty *OrdMap[*myKey, int]
+	c := empty.Cursor()
+	require.False(t, c.First())
+	require.False(t, c.Last())
+	require.False(t, c.Seek(intKey(0)))
+	require.False(t, c.SeekBackward(intKey(0)))
+	require.False(t, c.Next())
+	require.False(t, c.Prev())
+	require.Nil(t, c.Key())
+	require.Equal(t, 0, c.Value())
+
+	sizes := []int{1, 2, 3, 5, 7, 13, 30, 100}
+	for _, N := range sizes {
+		t.Run(fmt.Sprintf("size_%03d", N), func(t *testing.T) {
+			m := NewModel(t)
+			for i := 0; i < N; i++ {
+				m.Insert(intKey(i*2), i*2) // even keys only
+			}
+			c := m.tree.Cursor()
+			current := func() Entry[*myKey, int] {
+				return Entry[*myKey, int]{c.Key(), c.Value()}
+			}
+
+			entries := []Entry[*myKey, int]{}
+			for ok := c.First(); ok; ok = c.Next() {
+				entries = append(entries, current())
+			}
+			require.Equal(t, m.entries, entries)
+
+			entries = []Entry[*myKey, int]{}
+			for ok := c.Last(); ok; ok = c.Prev() {
+				entries = append([]Entry[*myKey, int]{current()}, entries...)
+			}
+			require.Equal(t, m.entries, entries)
+
+			for k := -1; k <= N*2; k++ {
+				index := (k + 1) / 2 // first even key >= k
+				if c.Seek(intKey(k)) {
+					require.Equal(t, m.entries[index], current())
+				} else {
+					require.Equal(t, N, index)
+				}
+				index = k / 2 // last even key <= k
+				if k < 0 {
+					index = -1
+				} else if index >= N {
+					index = N - 1
+				}
+				if c.SeekBackward(intKey(k)) {
+					require.Equal(t, m.entries[index], current(), "%v", k)
+				} else {
+					require.Equal(t, -1, index)
+				}
+			}
+
+			// zig-zag through the whole map, changing direction at every step
+			require.True(t, c.First())
+			for i := 1; i < N; i++ {
+				require.True(t, c.Next())
+				require.True(t, c.Prev())
+				require.Equal(t, m.entries[i-1], current())
+				require.True(t, c.Next())
+				require.Equal(t, m.entries[i], current())
+			}
+			require.False(t, c.Next())
+		})
+	}
+}
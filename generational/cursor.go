@@ -0,0 +1,189 @@
+package generational
+
+import "github.com/edofic/go-ordmap/v2"
+
+// Cursor is a bidirectional iterator over a generational map.
+// It merges cursors over both generations, hiding tombstoned and shadowed entries,
+// and does not allocate once created.
+//
+// A new cursor is not positioned; call First, Last, Seek or SeekBackward before reading.
+type Cursor[K ordmap.Comparable[K], V any] struct {
+	young *ordmap.Cursor[K, operation[V]]
+	old   *ordmap.Cursor[K, V]
+	// While moving forward both underlying cursors are positioned at or after the
+	// current key, while moving backward at or before it.
+	forward bool
+	valid   bool
+	key     K
+	value   V
+}
+
+// Cursor returns a new unpositioned cursor over the map.
+func (m *Map[K, V]) Cursor() *Cursor[K, V] {
+	if m == nil {
+		m = New[K, V](0)
+	}
+	return &Cursor[K, V]{
+		young: m.young.Cursor(),
+		old:   m.old.Cursor(),
+	}
+}
+
+// Valid reports whether the cursor is positioned at an entry.
+func (c *Cursor[K, V]) Valid() bool {
+	return c.valid
+}
+
+// Key returns the key at the current position, or the zero value if the cursor is not valid.
+func (c *Cursor[K, V]) Key() K {
+	return c.key
+}
+
+// Value returns the value at the current position, or the zero value if the cursor is not valid.
+func (c *Cursor[K, V]) Value() V {
+	return c.value
+}
+
+// First moves the cursor to the smallest key.
+// Returns false if the map is empty.
+func (c *Cursor[K, V]) First() bool {
+	c.young.First()
+	c.old.First()
+	return c.settleForward()
+}
+
+// Last moves the cursor to the largest key.
+// Returns false if the map is empty.
+func (c *Cursor[K, V]) Last() bool {
+	c.young.Last()
+	c.old.Last()
+	return c.settleBackward()
+}
+
+// Seek moves the cursor to the first key >= key.
+// Returns false if there is no such key.
+func (c *Cursor[K, V]) Seek(key K) bool {
+	c.young.Seek(key)
+	c.old.Seek(key)
+	return c.settleForward()
+}
+
+// SeekBackward moves the cursor to the last key <= key.
+// Returns false if there is no such key.
+func (c *Cursor[K, V]) SeekBackward(key K) bool {
+	c.young.SeekBackward(key)
+	c.old.SeekBackward(key)
+	return c.settleBackward()
+}
+
+// Next moves the cursor to the next larger key.
+// Returns false (and invalidates the cursor) when moving past the end.
+func (c *Cursor[K, V]) Next() bool {
+	if !c.valid {
+		return false
+	}
+	if c.forward {
+		// Whichever cursors sit at the current key need to move on.
+		if c.young.Valid() && !c.key.Less(c.young.Key()) {
+			c.young.Next()
+		}
+		if c.old.Valid() && !c.key.Less(c.old.Key()) {
+			c.old.Next()
+		}
+	} else {
+		// Changing direction: reposition both strictly after the current key.
+		if c.young.Seek(c.key) && !c.key.Less(c.young.Key()) {
+			c.young.Next()
+		}
+		if c.old.Seek(c.key) && !c.key.Less(c.old.Key()) {
+			c.old.Next()
+		}
+	}
+	return c.settleForward()
+}
+
+// Prev moves the cursor to the next smaller key.
+// Returns false (and invalidates the cursor) when moving past the beginning.
+func (c *Cursor[K, V]) Prev() bool {
+	if !c.valid {
+		return false
+	}
+	if !c.forward {
+		// Whichever cursors sit at the current key need to move on.
+		if c.young.Valid() && !c.young.Key().Less(c.key) {
+			c.young.Prev()
+		}
+		if c.old.Valid() && !c.old.Key().Less(c.key) {
+			c.old.Prev()
+		}
+	} else {
+		// Changing direction: reposition both strictly before the current key.
+		if c.young.SeekBackward(c.key) && !c.young.Key().Less(c.key) {
+			c.young.Prev()
+		}
+		if c.old.SeekBackward(c.key) && !c.old.Key().Less(c.key) {
+			c.old.Prev()
+		}
+	}
+	return c.settleBackward()
+}
+
+// settleForward picks the smaller of the two underlying positions, skipping tombstones.
+func (c *Cursor[K, V]) settleForward() bool {
+	c.forward = true
+	for {
+		youngOk, oldOk := c.young.Valid(), c.old.Valid()
+		if youngOk && (!oldOk || !c.old.Key().Less(c.young.Key())) {
+			// young is at or before old; on equal keys young shadows old
+			op := c.young.Value()
+			if op.delete {
+				if oldOk && !c.young.Key().Less(c.old.Key()) {
+					c.old.Next()
+				}
+				c.young.Next()
+				continue
+			}
+			return c.set(c.young.Key(), op.value)
+		}
+		if oldOk {
+			return c.set(c.old.Key(), c.old.Value())
+		}
+		return c.clear()
+	}
+}
+
+// settleBackward picks the larger of the two underlying positions, skipping tombstones.
+func (c *Cursor[K, V]) settleBackward() bool {
+	c.forward = false
+	for {
+		youngOk, oldOk := c.young.Valid(), c.old.Valid()
+		if youngOk && (!oldOk || !c.young.Key().Less(c.old.Key())) {
+			// young is at or after old; on equal keys young shadows old
+			op := c.young.Value()
+			if op.delete {
+				if oldOk && !c.old.Key().Less(c.young.Key()) {
+					c.old.Prev()
+				}
+				c.young.Prev()
+				continue
+			}
+			return c.set(c.young.Key(), op.value)
+		}
+		if oldOk {
+			return c.set(c.old.Key(), c.old.Value())
+		}
+		return c.clear()
+	}
+}
+
+func (c *Cursor[K, V]) set(key K, value V) bool {
+	c.valid, c.key, c.value = true, key, value
+	return true
+}
+
+func (c *Cursor[K, V]) clear() bool {
+	var key K
+	var value V
+	c.valid, c.key, c.value = false, key, value
+	return false
+}
@@ -0,0 +1,127 @@
+package generational
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/edofic/go-ordmap/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	m := New[Int, int](1000)
+	for range 300 {
+		k := Int(rand.Intn(200))
+		if rand.Float64() < 0.7 {
+			m = m.Insert(k, rand.Int())
+		} else {
+			m = m.Remove(k)
+		}
+		if rand.Float64() < 0.01 {
+			m = m.flush(m.young)
+		}
+	}
+	var entries []ordmap.Entry[Int, int]
+	for k, v := range m.All() {
+		entries = append(entries, ordmap.Entry[Int, int]{K: k, V: v})
+	}
+	c := m.Cursor()
+	current := func() ordmap.Entry[Int, int] {
+		return ordmap.Entry[Int, int]{K: c.Key(), V: c.Value()}
+	}
+
+	var seen []ordmap.Entry[Int, int]
+	for ok := c.First(); ok; ok = c.Next() {
+		seen = append(seen, current())
+	}
+	require.Equal(t, entries, seen)
+	require.False(t, c.Next())
+
+	seen = nil
+	for ok := c.Last(); ok; ok = c.Prev() {
+		seen = append([]ordmap.Entry[Int, int]{current()}, seen...)
+	}
+	require.Equal(t, entries, seen)
+	require.False(t, c.Prev())
+
+	for k := Int(-1); k <= 201; k++ {
+		index := 0
+		for index < len(entries) && entries[index].K < k {
+			index++
+		}
+		if c.Seek(k) {
+			require.Equal(t, entries[index], current())
+		} else {
+			require.Equal(t, len(entries), index)
+		}
+		index = len(entries) - 1
+		for index >= 0 && entries[index].K > k {
+			index--
+		}
+		if c.SeekBackward(k) {
+			require.Equal(t, entries[index], current())
+		} else {
+			require.Equal(t, -1, index)
+		}
+	}
+
+	// zig-zag through the whole map, changing direction at every step
+	require.True(t, c.First())
+	for i := 1; i < len(entries); i++ {
+		require.True(t, c.Next())
+		require.True(t, c.Prev())
+		require.Equal(t, entries[i-1], current())
+		require.True(t, c.Next())
+		require.Equal(t, entries[i], current())
+	}
+}
+
+func TestCursorShadowing(t *testing.T) {
+	m := New[Int, string](10)
+	for _, k := range []Int{1, 2, 3, 4, 5} {
+		m = m.Insert(k, "old")
+	}
+	m = m.flush(m.young)
+	m = m.Remove(1)
+	m = m.Remove(3)
+	m = m.Remove(5)
+	m = m.Insert(2, "young")
+	m = m.Insert(6, "young")
+	// Effective: {2: young, 4: old, 6: young}
+
+	c := m.Cursor()
+	var keys []Int
+	var values []string
+	for ok := c.First(); ok; ok = c.Next() {
+		keys = append(keys, c.Key())
+		values = append(values, c.Value())
+	}
+	require.Equal(t, []Int{2, 4, 6}, keys)
+	require.Equal(t, []string{"young", "old", "young"}, values)
+
+	keys = nil
+	for ok := c.Last(); ok; ok = c.Prev() {
+		keys = append(keys, c.Key())
+	}
+	require.Equal(t, []Int{6, 4, 2}, keys)
+	require.False(t, c.Valid())
+	require.Equal(t, Int(0), c.Key())
+
+	var empty *Map[Int, string]
+	require.False(t, empty.Cursor().First())
+}
+
+func TestCursorAllocations(t *testing.T) {
+	m := New[Int, int](100)
+	for i := range 1000 {
+		m = m.Insert(Int(i), i)
+	}
+	c := m.Cursor()
+	allocs := testing.AllocsPerRun(10, func() {
+		for ok := c.First(); ok; ok = c.Next() {
+		}
+		for ok := c.Last(); ok; ok = c.Prev() {
+		}
+	})
+	require.Equal(t, 0.0, allocs)
+}
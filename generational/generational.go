@@ -12,6 +12,7 @@ package generational
 
 import (
 	"iter"
+	"slices"
 
 	"github.com/edofic/go-ordmap/v2"
 )
@@ -77,6 +78,7 @@ func (m *Map[K, V]) Insert(key K, value V) *Map[K, V] {
 // Remove deletes the key from the map.
 // It inserts a tombstone into the young generation, effectively masking the key
 // from the old generation.
+// If the key does not exist, the map is returned unchanged (the very same pointer).
 func (m *Map[K, V]) Remove(key K) *Map[K, V] {
 	_, inOld := m.old.Get(key)
 	if !inOld {
@@ -84,6 +86,9 @@ func (m *Map[K, V]) Remove(key K) *Map[K, V] {
 		// If it's in young, it will be removed.
 		// If it's not in young, nothing happens (which is correct).
 		young := m.young.Remove(key)
+		if young == m.young {
+			return m
+		}
 		return &Map[K, V]{
 			young: young,
 			old:   m.old,
@@ -91,6 +96,9 @@ func (m *Map[K, V]) Remove(key K) *Map[K, V] {
 		}
 	}
 	// It is in old, so we must mask it with a tombstone in young.
+	if op, ok := m.young.Get(key); ok && op.delete {
+		return m // already masked
+	}
 	op := operation[V]{delete: true}
 	young := m.young.Insert(key, op)
 	if young.Len() >= m.limit {
@@ -103,6 +111,86 @@ func (m *Map[K, V]) Remove(key K) *Map[K, V] {
 	}
 }
 
+// Update inserts, modifies or deletes the entry for key.
+// fn receives the current value (or the zero value) and whether the key exists.
+// It returns the new value and whether the entry should be kept in the map.
+// The change is recorded in the young generation in a single descent; if the key
+// does not exist and fn does not keep it, the map is returned unchanged.
+func (m *Map[K, V]) Update(key K, fn func(old V, exists bool) (V, bool)) *Map[K, V] {
+	unchanged := false
+	young := m.young.Update(key, func(op operation[V], inYoung bool) (operation[V], bool) {
+		var old V
+		var exists, inOld bool
+		if inYoung {
+			old, exists = op.value, !op.delete
+		} else {
+			old, inOld = m.old.Get(key)
+			exists = inOld
+		}
+		value, keep := fn(old, exists)
+		if keep {
+			return operation[V]{value: value}, true
+		}
+		if !exists {
+			// Nothing to delete, leave the young generation as it was.
+			unchanged = true
+			return op, inYoung
+		}
+		if inYoung {
+			// The young generation shadows old, which we have not looked at yet.
+			_, inOld = m.old.Get(key)
+		}
+		if inOld {
+			// Mask it with a tombstone, same as Remove.
+			return operation[V]{delete: true}, true
+		}
+		return op, false
+	})
+	if unchanged {
+		return m
+	}
+	if young.Len() >= m.limit {
+		return m.flush(young)
+	}
+	return &Map[K, V]{
+		young: young,
+		old:   m.old,
+		limit: m.limit,
+	}
+}
+
+// RemoveRange deletes all keys between lo and hi.
+// opts controls whether each end is inclusive, exclusive or unbounded.
+// Instead of masking every affected key with a tombstone (which would flood the
+// young generation and trigger flushes), the range is cut out of both generations
+// directly. Range removal only costs O(log N) per generation, comparable to a
+// single write.
+// If no keys fall within the range, the map is returned unchanged.
+func (m *Map[K, V]) RemoveRange(lo, hi K, opts ordmap.RangeOptions) *Map[K, V] {
+	young := m.young.RemoveRange(lo, hi, opts)
+	old := m.old.RemoveRange(lo, hi, opts)
+	if young == m.young && old == m.old {
+		return m
+	}
+	return &Map[K, V]{
+		young: young,
+		old:   old,
+		limit: m.limit,
+	}
+}
+
+// TruncateBefore deletes all keys strictly less than key.
+// See RemoveRange for how this avoids per-key tombstones.
+func (m *Map[K, V]) TruncateBefore(key K) *Map[K, V] {
+	return m.RemoveRange(key, key, ordmap.RangeOptions{Lo: ordmap.Unbounded, Hi: ordmap.Exclusive})
+}
+
+// TruncateAfter deletes all keys strictly greater than key.
+// See RemoveRange for how this avoids per-key tombstones.
+func (m *Map[K, V]) TruncateAfter(key K) *Map[K, V] {
+	return m.RemoveRange(key, key, ordmap.RangeOptions{Lo: ordmap.Exclusive, Hi: ordmap.Unbounded})
+}
+
 func (m *Map[K, V]) flush(young *ordmap.Node[K, operation[V]]) *Map[K, V] {
 	old := m.old
 	for k, op := range young.All() {
@@ -270,6 +358,138 @@ func (m *Map[K, V]) BackwardFrom(k K) iter.Seq2[K, V] {
 	return mergeBackward(m.young.BackwardFrom(k), m.old.BackwardFrom(k))
 }
 
+// Range returns an iterator over key-value pairs with keys between lo and hi.
+// opts controls whether each end is inclusive, exclusive or unbounded.
+// The iteration proceeds in ascending order.
+func (m *Map[K, V]) Range(lo, hi K, opts ordmap.RangeOptions) iter.Seq2[K, V] {
+	if m == nil {
+		return func(func(K, V) bool) {}
+	}
+	return mergeForward(m.young.Range(lo, hi, opts), m.old.Range(lo, hi, opts))
+}
+
+// BackwardRange returns an iterator over key-value pairs with keys between lo and hi.
+// opts controls whether each end is inclusive, exclusive or unbounded.
+// The iteration proceeds in descending order.
+func (m *Map[K, V]) BackwardRange(lo, hi K, opts ordmap.RangeOptions) iter.Seq2[K, V] {
+	if m == nil {
+		return func(func(K, V) bool) {}
+	}
+	return mergeBackward(m.young.BackwardRange(lo, hi, opts), m.old.BackwardRange(lo, hi, opts))
+}
+
+// Keys returns an iterator over all keys in the map in ascending order.
+func (m *Map[K, V]) Keys() iter.Seq[K] {
+	return keysOf(m.All())
+}
+
+// Values returns an iterator over all values in the map in ascending order of their keys.
+func (m *Map[K, V]) Values() iter.Seq[V] {
+	return valuesOf(m.All())
+}
+
+// KeysSlice returns a slice of all keys in the map in ascending order.
+func (m *Map[K, V]) KeysSlice() []K {
+	return slices.Collect(m.Keys())
+}
+
+// ValuesSlice returns a slice of all values in the map in ascending order of their keys.
+func (m *Map[K, V]) ValuesSlice() []V {
+	return slices.Collect(m.Values())
+}
+
+// KeysRange returns an iterator over the keys between lo and hi in ascending order.
+// See Range for details.
+func (m *Map[K, V]) KeysRange(lo, hi K, opts ordmap.RangeOptions) iter.Seq[K] {
+	return keysOf(m.Range(lo, hi, opts))
+}
+
+// ValuesRange returns an iterator over the values of keys between lo and hi
+// in ascending order of their keys.
+// See Range for details.
+func (m *Map[K, V]) ValuesRange(lo, hi K, opts ordmap.RangeOptions) iter.Seq[V] {
+	return valuesOf(m.Range(lo, hi, opts))
+}
+
+func keysOf[K, V any](seq iter.Seq2[K, V]) iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k := range seq {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+func valuesOf[K, V any](seq iter.Seq2[K, V]) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, v := range seq {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// neighbor finds the closest live entry to key in the requested direction.
+// Candidates from both generations are compared and the closer one wins, with young
+// shadowing old on equal keys. A tombstone candidate means we have to continue the
+// search past it.
+func (m *Map[K, V]) neighbor(key K, inclusive, ascending bool) *ordmap.Entry[K, V] {
+	if m == nil {
+		return nil
+	}
+	for {
+		var young *ordmap.Entry[K, operation[V]]
+		var old *ordmap.Entry[K, V]
+		switch {
+		case ascending && inclusive:
+			young, old = m.young.Ceiling(key), m.old.Ceiling(key)
+		case ascending:
+			young, old = m.young.Higher(key), m.old.Higher(key)
+		case inclusive:
+			young, old = m.young.Floor(key), m.old.Floor(key)
+		default:
+			young, old = m.young.Lower(key), m.old.Lower(key)
+		}
+		if young == nil {
+			// young has nothing between key and old, so old is not shadowed
+			return old
+		}
+		if old != nil && (ascending && old.K.Less(young.K) || !ascending && young.K.Less(old.K)) {
+			return old
+		}
+		if !young.V.delete {
+			return &ordmap.Entry[K, V]{K: young.K, V: young.V.value}
+		}
+		key, inclusive = young.K, false
+	}
+}
+
+// Floor returns the entry with the greatest key less than or equal to key.
+// Returns nil if there is no such entry.
+func (m *Map[K, V]) Floor(key K) *ordmap.Entry[K, V] {
+	return m.neighbor(key, true, false)
+}
+
+// Ceiling returns the entry with the least key greater than or equal to key.
+// Returns nil if there is no such entry.
+func (m *Map[K, V]) Ceiling(key K) *ordmap.Entry[K, V] {
+	return m.neighbor(key, true, true)
+}
+
+// Lower returns the entry with the greatest key strictly less than key.
+// Returns nil if there is no such entry.
+func (m *Map[K, V]) Lower(key K) *ordmap.Entry[K, V] {
+	return m.neighbor(key, false, false)
+}
+
+// Higher returns the entry with the least key strictly greater than key.
+// Returns nil if there is no such entry.
+func (m *Map[K, V]) Higher(key K) *ordmap.Entry[K, V] {
+	return m.neighbor(key, false, true)
+}
+
 // Min returns the entry with the smallest key in the map.
 // It accounts for deletions and updates in the young generation.
 // Returns nil if the map is empty.
@@ -289,3 +509,66 @@ func (m *Map[K, V]) Max() *ordmap.Entry[K, V] {
 	}
 	return nil
 }
+
+// PopMin removes the entry with the smallest key.
+// Returns the removed entry and the new map, or nil and the unchanged map if it is empty.
+func (m *Map[K, V]) PopMin() (*ordmap.Entry[K, V], *Map[K, V]) {
+	return m.popExtreme(false)
+}
+
+// PopMax removes the entry with the largest key.
+// Returns the removed entry and the new map, or nil and the unchanged map if it is empty.
+func (m *Map[K, V]) PopMax() (*ordmap.Entry[K, V], *Map[K, V]) {
+	return m.popExtreme(true)
+}
+
+// popExtreme removes the entry with the smallest key, or the largest one if last is set.
+// Rather than finding the entry through a merge and then removing it with a tombstone,
+// both generations are popped at the same end, young shadowing old on equal keys.
+// A tombstone met on the way is dropped along with the entry it masks, which leaves
+// the contents unchanged, and the search continues past it.
+func (m *Map[K, V]) popExtreme(last bool) (*ordmap.Entry[K, V], *Map[K, V]) {
+	if m == nil {
+		return nil, m
+	}
+	peekYoung, popYoung := (*ordmap.Node[K, operation[V]]).Min, (*ordmap.Node[K, operation[V]]).PopMin
+	peekOld, popOld := (*ordmap.Node[K, V]).Min, (*ordmap.Node[K, V]).PopMin
+	before := func(a, b K) bool { return a.Less(b) }
+	if last {
+		peekYoung, popYoung = (*ordmap.Node[K, operation[V]]).Max, (*ordmap.Node[K, operation[V]]).PopMax
+		peekOld, popOld = (*ordmap.Node[K, V]).Max, (*ordmap.Node[K, V]).PopMax
+		before = func(a, b K) bool { return b.Less(a) }
+	}
+	young, old := m.young, m.old
+	for {
+		y, o := peekYoung(young), peekOld(old)
+		switch {
+		case y == nil && o == nil:
+			return nil, m
+		case y == nil || o != nil && before(o.K, y.K):
+			// not shadowed by young
+			_, old = popOld(old)
+			return o, &Map[K, V]{young: young, old: old, limit: m.limit}
+		case o != nil && !before(y.K, o.K):
+			// same key, young shadows old
+			_, old = popOld(old)
+		}
+		_, young = popYoung(young)
+		if !y.V.delete {
+			e := &ordmap.Entry[K, V]{K: y.K, V: y.V.value}
+			return e, &Map[K, V]{young: young, old: old, limit: m.limit}
+		}
+	}
+}
+
+// DeleteMin removes the entry with the smallest key.
+func (m *Map[K, V]) DeleteMin() *Map[K, V] {
+	_, rest := m.PopMin()
+	return rest
+}
+
+// DeleteMax removes the entry with the largest key.
+func (m *Map[K, V]) DeleteMax() *Map[K, V] {
+	_, rest := m.PopMax()
+	return rest
+}
@@ -1,8 +1,11 @@
 package generational
 
 import (
+	"fmt"
+	"slices"
 	"testing"
 
+	"github.com/edofic/go-ordmap/v2"
 	"github.com/stretchr/testify/require"
 )
 
@@ -221,4 +224,271 @@ func TestPartialIteration(t *testing.T) {
 		keys = append(keys, int(k))
 	}
 	require.Equal(t, []int{30, 25, 20, 10}, keys)
-}
\ No newline at end of file
+}
+
+func TestRange(t *testing.T) {
+	m := New[Int, string](2)
+	m = m.Insert(10, "10")
+	m = m.Insert(20, "20")
+	m = m.Insert(30, "30") // flushed. Old={10,20,30}, Young={}
+	m = m.Insert(40, "40")
+	m = m.Insert(50, "50") // flushed. Old={10,20,30,40,50}, Young={}
+	m = m.Insert(25, "25")
+	m = m.Remove(40)
+	// Old: {10, 20, 30, 40, 50}
+	// Young: {25, 40: DEL}
+
+	var keys []int
+	for k := range m.Range(20, 50, ordmap.RangeOptions{Hi: ordmap.Exclusive}) {
+		keys = append(keys, int(k))
+	}
+	require.Equal(t, []int{20, 25, 30}, keys)
+
+	keys = nil
+	for k := range m.Range(20, 0, ordmap.RangeOptions{Lo: ordmap.Exclusive, Hi: ordmap.Unbounded}) {
+		keys = append(keys, int(k))
+	}
+	require.Equal(t, []int{25, 30, 50}, keys)
+
+	keys = nil
+	for k := range m.BackwardRange(25, 45, ordmap.RangeOptions{}) {
+		keys = append(keys, int(k))
+	}
+	require.Equal(t, []int{30, 25}, keys)
+
+	var empty *Map[Int, string]
+	for range empty.Range(0, 100, ordmap.RangeOptions{}) {
+		t.Fatal("empty map yielded")
+	}
+	for range empty.BackwardRange(0, 100, ordmap.RangeOptions{}) {
+		t.Fatal("empty map yielded")
+	}
+}
+
+func TestKeysValues(t *testing.T) {
+	m := New[Int, string](2)
+	m = m.Insert(10, "10")
+	m = m.Insert(20, "20")
+	m = m.Insert(30, "30") // flushed. Old={10,20,30}, Young={}
+	m = m.Insert(25, "25")
+	m = m.Remove(20)
+	// Old: {10, 20, 30}
+	// Young: {20: DEL, 25}
+
+	require.Equal(t, []Int{10, 25, 30}, m.KeysSlice())
+	require.Equal(t, []string{"10", "25", "30"}, m.ValuesSlice())
+	require.Equal(t, m.KeysSlice(), slices.Collect(m.Keys()))
+	require.Equal(t, m.ValuesSlice(), slices.Collect(m.Values()))
+	require.Equal(t, []Int{25, 30}, slices.Collect(m.KeysRange(20, 30, ordmap.RangeOptions{})))
+	require.Equal(t, []string{"10", "25"}, slices.Collect(m.ValuesRange(0, 30, ordmap.RangeOptions{Hi: ordmap.Exclusive})))
+
+	var empty *Map[Int, string]
+	require.Empty(t, empty.KeysSlice())
+	require.Empty(t, empty.ValuesSlice())
+}
+
+func TestNeighbors(t *testing.T) {
+	m := New[Int, string](10)
+	for _, k := range []Int{10, 20, 25, 30, 40, 50} {
+		m = m.Insert(k, fmt.Sprint(k))
+	}
+	m = m.flush(m.young)
+	m = m.Remove(30)
+	m = m.Remove(40)
+	m = m.Remove(50)
+	m = m.Insert(20, "20-new")
+	m = m.Insert(45, "45")
+	// Old: {10, 20, 25, 30, 40, 50}
+	// Young: {20: "20-new", 30: DEL, 40: DEL, 45: "45", 50: DEL}
+	// Effective: {10, 20: "20-new", 25, 45}
+
+	key := func(e *ordmap.Entry[Int, string]) int {
+		if e == nil {
+			return -1
+		}
+		return int(e.K)
+	}
+
+	require.Equal(t, 45, key(m.Floor(49)))
+	require.Equal(t, 45, key(m.Floor(100))) // 50 is deleted
+	require.Equal(t, 25, key(m.Floor(44)))  // 30 and 40 are deleted
+	require.Equal(t, -1, key(m.Floor(5)))
+	require.Equal(t, "20-new", m.Floor(20).V)
+	require.Equal(t, "20-new", m.Floor(21).V)
+
+	require.Equal(t, 20, key(m.Ceiling(20)))
+	require.Equal(t, "20-new", m.Ceiling(11).V)
+	require.Equal(t, 45, key(m.Ceiling(26)))
+	require.Equal(t, -1, key(m.Ceiling(46))) // 50 is deleted
+
+	require.Equal(t, 10, key(m.Lower(20)))
+	require.Equal(t, 45, key(m.Lower(50)))
+	require.Equal(t, 25, key(m.Lower(45)))
+	require.Equal(t, -1, key(m.Lower(10)))
+
+	require.Equal(t, 25, key(m.Higher(20)))
+	require.Equal(t, 10, key(m.Higher(0)))
+	require.Equal(t, 45, key(m.Higher(25)))
+	require.Equal(t, -1, key(m.Higher(45)))
+
+	var empty *Map[Int, string]
+	require.Nil(t, empty.Floor(1))
+}
+
+func TestUpdate(t *testing.T) {
+	m := New[Int, int](10)
+	m = m.Insert(1, 1)
+	m = m.Insert(2, 2)
+	m = m.flush(m.young)
+	m = m.Insert(3, 3)
+	m = m.Remove(2)
+	// Old: {1, 2}
+	// Young: {2: DEL, 3}
+
+	increment := func(old int, exists bool) (int, bool) {
+		return old + 10, true
+	}
+	remove := func(old int, exists bool) (int, bool) {
+		return old, false
+	}
+
+	m1 := m.Update(1, increment) // in old
+	m1 = m1.Update(2, increment) // tombstoned
+	m1 = m1.Update(3, increment) // in young
+	m1 = m1.Update(4, increment) // missing
+	require.Equal(t, []ordmap.Entry[Int, int]{{K: 1, V: 11}, {K: 2, V: 10}, {K: 3, V: 13}, {K: 4, V: 10}}, collect(m1))
+
+	m2 := m.Update(1, remove) // in old, needs a tombstone
+	require.Equal(t, []ordmap.Entry[Int, int]{{K: 3, V: 3}}, collect(m2))
+	op, ok := m2.young.Get(1)
+	require.True(t, ok)
+	require.True(t, op.delete)
+
+	m3 := m.Update(3, remove) // young only, dropped entirely
+	require.Equal(t, []ordmap.Entry[Int, int]{{K: 1, V: 1}}, collect(m3))
+	require.Equal(t, 1, m3.young.Len())
+
+	require.Same(t, m, m.Update(2, remove)) // already tombstoned
+	require.Same(t, m, m.Update(5, remove)) // missing
+
+	m4 := New[Int, int](2)
+	m4 = m4.Update(1, increment)
+	m4 = m4.Update(2, increment) // flush
+	require.Equal(t, 0, m4.young.Len())
+	require.Equal(t, 2, m4.old.Len())
+}
+
+func collect(m *Map[Int, int]) []ordmap.Entry[Int, int] {
+	var entries []ordmap.Entry[Int, int]
+	for k, v := range m.All() {
+		entries = append(entries, ordmap.Entry[Int, int]{K: k, V: v})
+	}
+	return entries
+}
+
+func TestRemoveMissing(t *testing.T) {
+	m := New[Int, int](10)
+	m = m.Insert(1, 1)
+	m = m.flush(m.young)
+	m = m.Insert(2, 2)
+	m = m.Remove(1)
+
+	require.Same(t, m, m.Remove(1)) // already tombstoned
+	require.Same(t, m, m.Remove(3)) // never existed
+	require.NotSame(t, m, m.Remove(2))
+}
+
+func TestPop(t *testing.T) {
+	m := New[Int, int](3)
+	for _, k := range []Int{5, 3, 8, 1, 9, 2} {
+		m = m.Insert(k, int(k))
+	}
+	m = m.Remove(9)
+
+	var keys []int
+	queue := m
+	for {
+		var e *ordmap.Entry[Int, int]
+		e, queue = queue.PopMin()
+		if e == nil {
+			break
+		}
+		keys = append(keys, int(e.K))
+	}
+	require.Equal(t, []int{1, 2, 3, 5, 8}, keys)
+
+	keys = nil
+	queue = m
+	for {
+		var e *ordmap.Entry[Int, int]
+		e, queue = queue.PopMax()
+		if e == nil {
+			break
+		}
+		keys = append(keys, int(e.K))
+	}
+	require.Equal(t, []int{8, 5, 3, 2, 1}, keys)
+
+	require.Equal(t, Int(2), m.DeleteMin().Min().K)
+	require.Equal(t, Int(5), m.DeleteMax().Max().K)
+	require.Equal(t, Int(1), m.Min().K) // persistence
+
+	// tombstones and overrides at both ends
+	m = New[Int, int](10)
+	for k := Int(1); k <= 5; k++ {
+		m = m.Insert(k, int(k))
+	}
+	m = m.flush(m.young)
+	m = m.Remove(1).Remove(5).Insert(2, 20).Insert(4, 40)
+	e, rest := m.PopMin()
+	require.Equal(t, ordmap.Entry[Int, int]{K: 2, V: 20}, *e)
+	require.Equal(t, []ordmap.Entry[Int, int]{{K: 3, V: 3}, {K: 4, V: 40}}, collect(rest))
+	e, rest = m.PopMax()
+	require.Equal(t, ordmap.Entry[Int, int]{K: 4, V: 40}, *e)
+	require.Equal(t, []ordmap.Entry[Int, int]{{K: 2, V: 20}, {K: 3, V: 3}}, collect(rest))
+	e, rest = rest.PopMax()
+	require.Equal(t, ordmap.Entry[Int, int]{K: 3, V: 3}, *e)
+	require.Equal(t, []ordmap.Entry[Int, int]{{K: 2, V: 20}}, collect(rest))
+
+	// only tombstones left
+	m = m.Remove(2).Remove(3).Remove(4)
+	e, rest = m.PopMin()
+	require.Nil(t, e)
+	require.Same(t, m, rest)
+}
+
+func TestRemoveRange(t *testing.T) {
+	m := New[Int, int](100)
+	for i := Int(0); i < 50; i++ {
+		m = m.Insert(i, int(i))
+	}
+	m = m.flush(m.young)
+	for i := Int(50); i < 60; i++ {
+		m = m.Insert(i, int(i))
+	}
+	m = m.Remove(10)
+	// Old: [0, 50)
+	// Young: [50, 60), 10: DEL
+
+	keys := func(m *Map[Int, int]) []int {
+		var keys []int
+		for k := range m.All() {
+			keys = append(keys, int(k))
+		}
+		return keys
+	}
+
+	expired := m.TruncateBefore(45)
+	require.Equal(t, []int{45, 46, 47, 48, 49, 50, 51, 52, 53, 54, 55, 56, 57, 58, 59}, keys(expired))
+	require.Equal(t, 10, expired.young.Len()) // no tombstones added, 10 dropped
+
+	trimmed := m.TruncateAfter(5)
+	require.Equal(t, []int{0, 1, 2, 3, 4, 5}, keys(trimmed))
+	require.Equal(t, 0, trimmed.young.Len())
+
+	cut := m.RemoveRange(2, 57, ordmap.RangeOptions{Hi: ordmap.Exclusive})
+	require.Equal(t, []int{0, 1, 57, 58, 59}, keys(cut))
+
+	require.Same(t, m, m.RemoveRange(100, 200, ordmap.RangeOptions{}))
+	require.Equal(t, 59, len(keys(m))) // persistence
+}
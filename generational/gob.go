@@ -0,0 +1,66 @@
+package generational
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+
+	"github.com/edofic/go-ordmap/v2"
+)
+
+// gobMap is the gob representation of a Map, with exported fields as gob requires.
+type gobMap[K, V any] struct {
+	Limit int
+	Old   []ordmap.Entry[K, V]
+	Young []gobOperation[K, V]
+}
+
+type gobOperation[K, V any] struct {
+	Key    K
+	Value  V
+	Delete bool
+}
+
+// GobEncode encodes both generations of the map, tombstones included.
+func (m *Map[K, V]) GobEncode() ([]byte, error) {
+	if m == nil {
+		m = New[K, V](0)
+	}
+	g := gobMap[K, V]{Limit: m.limit, Old: m.old.Entries()}
+	g.Young = make([]gobOperation[K, V], 0, m.young.Len())
+	for k, op := range m.young.All() {
+		g.Young = append(g.Young, gobOperation[K, V]{k, op.value, op.delete})
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(g); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode replaces the map with the encoded one.
+// Both generations are rebuilt into perfectly balanced trees in O(N).
+func (m *Map[K, V]) GobDecode(data []byte) error {
+	var g gobMap[K, V]
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&g); err != nil {
+		return err
+	}
+	young := make([]ordmap.Entry[K, operation[V]], len(g.Young))
+	for i, op := range g.Young {
+		young[i] = ordmap.Entry[K, operation[V]]{K: op.Key, V: operation[V]{op.Value, op.Delete}}
+	}
+	if !sorted(young) || !sorted(g.Old) {
+		return errors.New("generational: gob: keys out of order")
+	}
+	*m = Map[K, V]{young: ordmap.FromSorted(young), old: ordmap.FromSorted(g.Old), limit: g.Limit}
+	return nil
+}
+
+func sorted[K ordmap.Comparable[K], V any](entries []ordmap.Entry[K, V]) bool {
+	for i := 1; i < len(entries); i++ {
+		if !entries[i-1].K.Less(entries[i].K) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+package generational
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+
+	"github.com/edofic/go-ordmap/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGob(t *testing.T) {
+	m := New[Int, int](10).Insert(1, 10).Insert(2, 20)
+	m = m.flush(m.young).Remove(1).Insert(3, 30)
+
+	var buf bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&buf).Encode(m))
+	var decoded *Map[Int, int]
+	require.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+	require.Equal(t, m.limit, decoded.limit)
+	require.Equal(t, m.young.Entries(), decoded.young.Entries())
+	require.Equal(t, m.old.Entries(), decoded.old.Entries())
+	require.Equal(t, []ordmap.Entry[Int, int]{{K: 2, V: 20}, {K: 3, V: 30}}, collect(decoded))
+
+	var empty *Map[Int, int]
+	data, err := empty.GobEncode()
+	require.NoError(t, err)
+	require.NoError(t, decoded.GobDecode(data))
+	require.Empty(t, collect(decoded))
+
+	var g bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&g).Encode(gobMap[Int, int]{Old: []ordmap.Entry[Int, int]{{K: 2}, {K: 1}}}))
+	require.ErrorContains(t, decoded.GobDecode(g.Bytes()), "out of order")
+	require.Error(t, decoded.GobDecode([]byte("garbage")))
+}
@@ -0,0 +1,181 @@
+package generational
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/edofic/go-ordmap/v2"
+)
+
+// A snapshot of a generational map is a header followed by ordmap snapshots of
+// the old and the young generation. The header is laid out as follows:
+//
+//	magic     "ordg"
+//	version   1 byte
+//	limit     8 bytes little endian
+//	checksum  CRC-32C of the above, 4 bytes little endian
+//
+// Values in the young generation are prefixed with a byte telling tombstones apart.
+const (
+	snapshotMagic      = "ordg"
+	snapshotVersion    = 1
+	snapshotHeaderSize = len(snapshotMagic) + 1 + 8 + 4
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Encoder writes snapshots of generational maps to a stream,
+// preserving the split between generations and the tombstones.
+type Encoder[K ordmap.Comparable[K], V any] struct {
+	w     *bufio.Writer
+	old   *ordmap.Encoder[K, V]
+	young *ordmap.Encoder[K, operation[V]]
+}
+
+// NewEncoder returns an encoder writing to w with the given codecs.
+// See ordmap.NewEncoder for details.
+func NewEncoder[K ordmap.Comparable[K], V any](w io.Writer, keys ordmap.Codec[K], values ordmap.Codec[V]) *Encoder[K, V] {
+	bw, ok := w.(*bufio.Writer)
+	if !ok {
+		bw = bufio.NewWriter(w)
+	}
+	return &Encoder[K, V]{
+		w:     bw,
+		old:   ordmap.NewEncoder(bw, keys, values),
+		young: ordmap.NewEncoder(bw, keys, operationCodec[V]{values}),
+	}
+}
+
+// Encode writes a complete snapshot of m and flushes it to the underlying writer.
+func (e *Encoder[K, V]) Encode(m *Map[K, V]) error {
+	if m == nil {
+		m = New[K, V](0)
+	}
+	header := make([]byte, 0, snapshotHeaderSize)
+	header = append(header, snapshotMagic...)
+	header = append(header, snapshotVersion)
+	header = binary.LittleEndian.AppendUint64(header, uint64(m.limit))
+	header = binary.LittleEndian.AppendUint32(header, crc32.Checksum(header, castagnoli))
+	if _, err := e.w.Write(header); err != nil {
+		return err
+	}
+	if err := e.old.Encode(m.old); err != nil {
+		return err
+	}
+	return e.young.Encode(m.young)
+}
+
+// Decoder reads snapshots of generational maps from a stream.
+// See ordmap.Decoder for details.
+type Decoder[K ordmap.Comparable[K], V any] struct {
+	r     *bufio.Reader
+	old   *ordmap.Decoder[K, V]
+	young *ordmap.Decoder[K, operation[V]]
+}
+
+// NewDecoder returns a decoder reading from r with the given codecs.
+// See ordmap.NewDecoder for details.
+func NewDecoder[K ordmap.Comparable[K], V any](r io.Reader, keys ordmap.Codec[K], values ordmap.Codec[V]) *Decoder[K, V] {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Decoder[K, V]{
+		r:     br,
+		old:   ordmap.NewDecoder(br, keys, values),
+		young: ordmap.NewDecoder(br, keys, operationCodec[V]{values}),
+	}
+}
+
+// Decode reads the next snapshot from the stream.
+// It returns io.EOF if the stream ends cleanly before a snapshot and
+// an error wrapping ordmap.ErrInvalidSnapshot if the data is corrupt.
+func (d *Decoder[K, V]) Decode() (*Map[K, V], error) {
+	var header [snapshotHeaderSize]byte
+	if _, err := io.ReadFull(d.r, header[:]); err != nil {
+		return nil, err
+	}
+	body, crc := header[:snapshotHeaderSize-4], header[snapshotHeaderSize-4:]
+	switch {
+	case string(body[:len(snapshotMagic)]) != snapshotMagic:
+		return nil, fmt.Errorf("%w: bad magic", ordmap.ErrInvalidSnapshot)
+	case binary.LittleEndian.Uint32(crc) != crc32.Checksum(body, castagnoli):
+		return nil, fmt.Errorf("%w: checksum mismatch", ordmap.ErrInvalidSnapshot)
+	case body[len(snapshotMagic)] != snapshotVersion:
+		return nil, fmt.Errorf("%w: unsupported version %d", ordmap.ErrInvalidSnapshot, body[len(snapshotMagic)])
+	}
+	limit := int(binary.LittleEndian.Uint64(body[len(snapshotMagic)+1:]))
+	old, err := d.old.Decode()
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	young, err := d.young.Decode()
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	return &Map[K, V]{young: young, old: old, limit: limit}, nil
+}
+
+// MarshalSnapshot encodes m as a snapshot, see Encoder.
+func MarshalSnapshot[K ordmap.Comparable[K], V any](m *Map[K, V], keys ordmap.Codec[K], values ordmap.Codec[V]) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf, keys, values).Encode(m); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalSnapshot decodes a snapshot produced by MarshalSnapshot, see Decoder.
+// Data following the snapshot is an error.
+func UnmarshalSnapshot[K ordmap.Comparable[K], V any](data []byte, keys ordmap.Codec[K], values ordmap.Codec[V]) (*Map[K, V], error) {
+	r := bytes.NewReader(data)
+	d := NewDecoder(r, keys, values)
+	m, err := d.Decode()
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	if r.Len() > 0 || d.r.Buffered() > 0 {
+		return nil, fmt.Errorf("%w: trailing data", ordmap.ErrInvalidSnapshot)
+	}
+	return m, nil
+}
+
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// operationCodec encodes young generation entries as a tag byte followed by the value.
+type operationCodec[V any] struct {
+	values ordmap.Codec[V]
+}
+
+const (
+	tagValue     = 0
+	tagTombstone = 1
+)
+
+func (c operationCodec[V]) Append(buf []byte, op operation[V]) ([]byte, error) {
+	if op.delete {
+		return append(buf, tagTombstone), nil
+	}
+	return c.values.Append(append(buf, tagValue), op.value)
+}
+
+func (c operationCodec[V]) Decode(data []byte) (operation[V], error) {
+	switch {
+	case len(data) == 1 && data[0] == tagTombstone:
+		return operation[V]{delete: true}, nil
+	case len(data) > 0 && data[0] == tagValue:
+		value, err := c.values.Decode(data[1:])
+		return operation[V]{value: value}, err
+	default:
+		return operation[V]{}, fmt.Errorf("%w: bad operation tag", ordmap.ErrInvalidSnapshot)
+	}
+}
@@ -0,0 +1,82 @@
+package generational
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/edofic/go-ordmap/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	keys, values := ordmap.IntCodec[Int]{}, ordmap.IntCodec[int]{}
+	m := New[Int, int](50)
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, keys, values)
+	var versions []*Map[Int, int]
+	for i := range 300 {
+		k := Int(rand.Intn(100))
+		if rand.Intn(3) == 0 {
+			m = m.Remove(k)
+		} else {
+			m = m.Insert(k, i)
+		}
+		if i%50 == 0 {
+			versions = append(versions, m)
+			require.NoError(t, enc.Encode(m))
+		}
+	}
+	require.NoError(t, enc.Encode(nil))
+
+	dec := NewDecoder(&buf, keys, values)
+	for _, version := range versions {
+		decoded, err := dec.Decode()
+		require.NoError(t, err)
+		// both generations come back as they were, tombstones included
+		require.Equal(t, version.limit, decoded.limit)
+		require.Equal(t, version.young.Entries(), decoded.young.Entries())
+		require.Equal(t, version.old.Entries(), decoded.old.Entries())
+		require.Equal(t, collect(version), collect(decoded))
+	}
+	empty, err := dec.Decode()
+	require.NoError(t, err)
+	require.Empty(t, collect(empty))
+	_, err = dec.Decode()
+	require.Equal(t, io.EOF, err)
+
+	m = New[Int, int](10).Insert(1, 1).Insert(2, 2)
+	m = m.flush(m.young).Remove(1).Insert(3, 3)
+	data, err := MarshalSnapshot(m, keys, values)
+	require.NoError(t, err)
+	decoded, err := UnmarshalSnapshot(data, keys, values)
+	require.NoError(t, err)
+	require.Equal(t, m.young.Entries(), decoded.young.Entries())
+	require.Equal(t, []ordmap.Entry[Int, int]{{K: 2, V: 2}, {K: 3, V: 3}}, collect(decoded))
+	decoded = decoded.Insert(4, 4)
+	require.Equal(t, 3, decoded.young.Len(), "keeps working with the same limit")
+}
+
+func TestSnapshotCorrupt(t *testing.T) {
+	keys, values := ordmap.IntCodec[Int]{}, ordmap.StringCodec[string]{}
+	m := New[Int, string](10).Insert(1, "a").Insert(2, "b")
+	m = m.flush(m.young).Remove(1).Insert(3, "c")
+	data, err := MarshalSnapshot(m, keys, values)
+	require.NoError(t, err)
+
+	for i := range data {
+		_, err := UnmarshalSnapshot(data[:i], keys, values)
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF, "truncated to %v", i)
+
+		corrupt := bytes.Clone(data)
+		corrupt[i] ^= 1 << (i % 8)
+		_, err = UnmarshalSnapshot(corrupt, keys, values)
+		require.Error(t, err, "corrupted at %v", i)
+	}
+	_, err = UnmarshalSnapshot(append(data, 0), keys, values)
+	require.ErrorIs(t, err, ordmap.ErrInvalidSnapshot)
+
+	_, err = operationCodec[string]{values}.Decode([]byte{2})
+	require.ErrorIs(t, err, ordmap.ErrInvalidSnapshot)
+}
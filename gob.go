@@ -0,0 +1,77 @@
+package ordmap
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+)
+
+// GobEncode encodes the entries of the map in key order.
+func (n NodeBuiltin[K, V]) GobEncode() ([]byte, error) {
+	return gobEncode(n.Entries())
+}
+
+// GobDecode replaces the contents of the map with the encoded entries.
+// They are rebuilt into a perfectly balanced tree in O(N). Entries that are not
+// in strictly ascending key order, which GobEncode never produces, are rejected.
+func (n *NodeBuiltin[K, V]) GobDecode(data []byte) error {
+	entries, err := gobDecode[K, V](data)
+	if err != nil {
+		return err
+	}
+	node, err := fromGob(wrapEntries(entries))
+	if err != nil {
+		return err
+	}
+	n.n = node
+	return nil
+}
+
+// GobNode makes a *Node usable with encoding/gob, which needs a non-nil value to
+// decode into. Both the keys and the values need to be encodable by gob themselves.
+type GobNode[K Comparable[K], V any] struct {
+	Node *Node[K, V]
+}
+
+// GobEncode encodes the entries of the map in key order.
+func (g GobNode[K, V]) GobEncode() ([]byte, error) {
+	return gobEncode(g.Node.Entries())
+}
+
+// GobDecode replaces the map with the encoded entries.
+// See NodeBuiltin.GobDecode for details.
+func (g *GobNode[K, V]) GobDecode(data []byte) error {
+	entries, err := gobDecode[K, V](data)
+	if err != nil {
+		return err
+	}
+	node, err := fromGob(entries)
+	if err != nil {
+		return err
+	}
+	g.Node = node
+	return nil
+}
+
+func gobEncode[K, V any](entries []Entry[K, V]) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(entries); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gobDecode[K, V any](data []byte) ([]Entry[K, V], error) {
+	var entries []Entry[K, V]
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entries)
+	return entries, err
+}
+
+func fromGob[K Comparable[K], V any](entries []Entry[K, V]) (*Node[K, V], error) {
+	for i := 1; i < len(entries); i++ {
+		if !entries[i-1].K.Less(entries[i].K) {
+			return nil, errors.New("ordmap: gob: keys out of order")
+		}
+	}
+	return FromSorted(entries), nil
+}
@@ -0,0 +1,83 @@
+package ordmap
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math/bits"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGob(t *testing.T) {
+	type message struct {
+		Counts NodeBuiltin[string, int]
+		Points GobNode[point, []string]
+		Empty  NodeBuiltin[int, int]
+		Name   string
+	}
+	var points *Node[point, []string]
+	for i := range 100 {
+		points = points.Insert(point{i % 3, -i}, []string{"p", string(rune('a' + i%26))})
+	}
+	sent := []message{
+		{
+			Counts: FromSortedBuiltin([]Entry[string, int]{{"a", 1}, {"b", 2}}),
+			Points: GobNode[point, []string]{points},
+			Name:   "first",
+		},
+		{Name: "second"}, // zero values
+	}
+
+	// several messages over one stream, as net/rpc does
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	for _, m := range sent {
+		require.NoError(t, enc.Encode(m))
+	}
+	dec := gob.NewDecoder(&buf)
+	for _, m := range sent {
+		var received message
+		require.NoError(t, dec.Decode(&received))
+		require.Equal(t, m.Name, received.Name)
+		require.Equal(t, m.Counts.Entries(), received.Counts.Entries())
+		require.Equal(t, m.Points.Node.Entries(), received.Points.Node.Entries())
+		require.Equal(t, 0, received.Empty.Len())
+		validateHeight(t, received.Points.Node)
+		validateOrdered(t, received.Points.Node)
+		require.Equal(t, bits.Len(uint(m.Points.Node.Len())), received.Points.Node.height())
+	}
+}
+
+func TestGobDecode(t *testing.T) {
+	data, err := gobEncode([]Entry[int, string]{{1, "a"}, {3, "c"}})
+	require.NoError(t, err)
+	var m NodeBuiltin[int, string]
+	require.NoError(t, m.GobDecode(data))
+	require.Equal(t, []Entry[int, string]{{1, "a"}, {3, "c"}}, m.Entries())
+
+	// same policy as generational.Map: input GobEncode cannot produce is rejected
+	for _, entries := range [][]Entry[int, string]{{{3, "c"}, {1, "a"}}, {{1, "a"}, {1, "b"}}} {
+		data, err := gobEncode(entries)
+		require.NoError(t, err)
+		require.EqualError(t, m.GobDecode(data), "ordmap: gob: keys out of order")
+		require.Equal(t, 2, m.Len()) // left as it was
+		points := make([]Entry[point, string], len(entries))
+		for i, e := range entries {
+			points[i] = Entry[point, string]{point{X: e.K}, e.V}
+		}
+		data, err = gobEncode(points)
+		require.NoError(t, err)
+		var g GobNode[point, string]
+		require.EqualError(t, g.GobDecode(data), "ordmap: gob: keys out of order")
+	}
+
+	var wrongType NodeBuiltin[string, string]
+	require.Error(t, wrongType.GobDecode(data))
+	var g GobNode[Builtin[int], string]
+	require.Error(t, g.GobDecode([]byte("garbage")))
+
+	// keys need to be encodable themselves
+	_, err = GobNode[Builtin[int], string]{New[Builtin[int], string]().Insert(Builtin[int]{1}, "a")}.GobEncode()
+	require.Error(t, err)
+}
@@ -0,0 +1,156 @@
+package ordmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"reflect"
+)
+
+// MarshalJSON encodes the map in key order. Maps with string keys become a JSON object,
+// all others an array of [key, value] pairs, since JSON object keys must be strings.
+func (n NodeBuiltin[K, V]) MarshalJSON() ([]byte, error) {
+	return marshalJSON(n.All())
+}
+
+// UnmarshalJSON replaces the contents of the map with the encoded entries.
+// It accepts the format produced by MarshalJSON, and null as the empty map.
+// Sorted input (such as the output of MarshalJSON) is rebuilt in O(N);
+// otherwise entries are sorted first and the last value wins for duplicate keys.
+func (n *NodeBuiltin[K, V]) UnmarshalJSON(data []byte) error {
+	entries, err := unmarshalJSON[K, V](data)
+	if err != nil {
+		return err
+	}
+	n.n = fromEntries(wrapEntries(entries))
+	return nil
+}
+
+// JSONNode makes a *Node usable with encoding/json. A nil *Node cannot be
+// unmarshalled into, so the map is held in a field instead.
+// The encoding is the same as for NodeBuiltin: a JSON object if the key type
+// has an underlying string type, an array of [key, value] pairs otherwise.
+type JSONNode[K Comparable[K], V any] struct {
+	Node *Node[K, V]
+}
+
+// MarshalJSON encodes the map in key order.
+func (j JSONNode[K, V]) MarshalJSON() ([]byte, error) {
+	return marshalJSON(j.Node.All())
+}
+
+// UnmarshalJSON replaces the map with the encoded entries.
+// See NodeBuiltin.UnmarshalJSON for details.
+func (j *JSONNode[K, V]) UnmarshalJSON(data []byte) error {
+	entries, err := unmarshalJSON[K, V](data)
+	if err != nil {
+		return err
+	}
+	j.Node = fromEntries(entries)
+	return nil
+}
+
+// jsonObjectKeys reports whether maps keyed by K are encoded as JSON objects.
+// Like encoding/json does for Go maps, such keys are used as strings directly.
+func jsonObjectKeys[K any]() bool {
+	return reflect.TypeFor[K]().Kind() == reflect.String
+}
+
+func marshalJSON[K, V any](seq iter.Seq2[K, V]) ([]byte, error) {
+	object := jsonObjectKeys[K]()
+	var buf bytes.Buffer
+	if object {
+		buf.WriteByte('{')
+	} else {
+		buf.WriteByte('[')
+	}
+	first := true
+	for k, v := range seq {
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		var key []byte
+		var err error
+		if object {
+			key, err = json.Marshal(reflect.ValueOf(k).String())
+		} else {
+			key, err = json.Marshal(k)
+		}
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		if object {
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(value)
+		} else {
+			buf.WriteByte('[')
+			buf.Write(key)
+			buf.WriteByte(',')
+			buf.Write(value)
+			buf.WriteByte(']')
+		}
+	}
+	if object {
+		buf.WriteByte('}')
+	} else {
+		buf.WriteByte(']')
+	}
+	return buf.Bytes(), nil
+}
+
+func unmarshalJSON[K, V any](data []byte) ([]Entry[K, V], error) {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil, nil
+	}
+	if !jsonObjectKeys[K]() {
+		var pairs [][]json.RawMessage
+		if err := json.Unmarshal(data, &pairs); err != nil {
+			return nil, err
+		}
+		entries := make([]Entry[K, V], len(pairs))
+		for i, pair := range pairs {
+			if len(pair) != 2 {
+				return nil, fmt.Errorf("ordmap: expected a [key, value] pair, got %d elements", len(pair))
+			}
+			if err := json.Unmarshal(pair[0], &entries[i].K); err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(pair[1], &entries[i].V); err != nil {
+				return nil, err
+			}
+		}
+		return entries, nil
+	}
+
+	// decode the object token by token, since going through a Go map would lose the order
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil {
+		return nil, err
+	} else if tok != json.Delim('{') {
+		return nil, fmt.Errorf("ordmap: expected a JSON object, got %v", tok)
+	}
+	var entries []Entry[K, V]
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var e Entry[K, V]
+		reflect.ValueOf(&e.K).Elem().SetString(tok.(string)) // object keys are always strings
+		if err := dec.Decode(&e.V); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	if _, err := dec.Token(); err != nil { // closing brace
+		return nil, err
+	}
+	return entries, nil
+}
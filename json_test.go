@@ -0,0 +1,113 @@
+package ordmap
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONBuiltin(t *testing.T) {
+	m := NewBuiltin[string, int]().Insert("b", 2).Insert("a", 1).Insert("c", 3)
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":1,"b":2,"c":3}`, string(data))
+
+	var decoded NodeBuiltin[string, int]
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, m.Entries(), decoded.Entries())
+
+	// non-string keys are encoded as pairs
+	ints := NewBuiltin[int, string]().Insert(10, "x").Insert(-1, "y")
+	data, err = json.Marshal(ints)
+	require.NoError(t, err)
+	require.Equal(t, `[[-1,"y"],[10,"x"]]`, string(data))
+	var decodedInts NodeBuiltin[int, string]
+	require.NoError(t, json.Unmarshal(data, &decodedInts))
+	require.Equal(t, ints.Entries(), decodedInts.Entries())
+
+	// named string types and nested maps
+	type name string
+	nested := NewBuiltin[name, NodeBuiltin[int, string]]().Insert("z", ints).Insert("y", NewBuiltin[int, string]())
+	data, err = json.Marshal(nested)
+	require.NoError(t, err)
+	require.Equal(t, `{"y":[],"z":[[-1,"y"],[10,"x"]]}`, string(data))
+	var decodedNested NodeBuiltin[name, NodeBuiltin[int, string]]
+	require.NoError(t, json.Unmarshal(data, &decodedNested))
+	z, _ := decodedNested.Get("z")
+	require.Equal(t, ints.Entries(), z.Entries())
+
+	// escaping
+	escaped := NewBuiltin[string, bool]().Insert(`"<key>"`, true)
+	data, err = json.Marshal(escaped)
+	require.NoError(t, err)
+	require.Equal(t, `{"\"\u003ckey\u003e\"":true}`, string(data))
+	var decodedEscaped NodeBuiltin[string, bool]
+	require.NoError(t, json.Unmarshal(data, &decodedEscaped))
+	require.Equal(t, escaped.Entries(), decodedEscaped.Entries())
+}
+
+func TestJSONUnmarshal(t *testing.T) {
+	// replaces the previous contents
+	m := NewBuiltin[string, int]().Insert("old", 0)
+	require.NoError(t, json.Unmarshal([]byte(`{"c":3,"a":1,"b":2,"a":4}`), &m))
+	require.Equal(t, []Entry[string, int]{{"a", 4}, {"b", 2}, {"c", 3}}, m.Entries())
+
+	pairs := NewBuiltin[int, int]()
+	require.NoError(t, json.Unmarshal([]byte(`[[3,3],[1,1],[3,4]]`), &pairs))
+	require.Equal(t, []Entry[int, int]{{1, 1}, {3, 4}}, pairs.Entries())
+
+	require.NoError(t, json.Unmarshal([]byte(`null`), &m))
+	require.Equal(t, 0, m.Len())
+	require.NoError(t, json.Unmarshal([]byte(`{}`), &m))
+	require.Equal(t, 0, m.Len())
+
+	for _, data := range []string{`[]`, `{"a":"x"}`, `{"a":1`, `1`} {
+		require.Error(t, json.Unmarshal([]byte(data), &m), data)
+	}
+	for _, data := range []string{`{}`, `[[1]]`, `[[1,2,3]]`, `[["a",1]]`, `[[1,"a"]]`} {
+		require.Error(t, json.Unmarshal([]byte(data), &pairs), data)
+	}
+
+	// as a field, alongside other values
+	var config struct {
+		Order NodeBuiltin[string, int] `json:"order"`
+		Name  string                   `json:"name"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"order":{"y":1,"x":2},"name":"n"}`), &config))
+	require.Equal(t, []string{"x", "y"}, config.Order.KeysSlice())
+	require.Equal(t, "n", config.Name)
+}
+
+// point is a composite key with a JSON representation of its own.
+type point struct {
+	X, Y int
+}
+
+func (p point) Less(other point) bool {
+	return p.X < other.X || p.X == other.X && p.Y < other.Y
+}
+
+func TestJSONNode(t *testing.T) {
+	var tree *Node[point, string]
+	for i := range 300 {
+		tree = tree.Insert(point{i % 7, i}, fmt.Sprint(i))
+	}
+	data, err := json.Marshal(JSONNode[point, string]{tree})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(data), `[[{"X":0,"Y":0},"0"],[{"X":0,"Y":7},"7"],`), string(data))
+
+	var decoded JSONNode[point, string]
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	validateHeight(t, decoded.Node)
+	validateOrdered(t, decoded.Node)
+	require.Equal(t, tree.Entries(), decoded.Node.Entries())
+
+	data, err = json.Marshal(JSONNode[point, string]{})
+	require.NoError(t, err)
+	require.Equal(t, `[]`, string(data))
+	require.NoError(t, json.Unmarshal([]byte(`null`), &decoded))
+	require.Nil(t, decoded.Node)
+}
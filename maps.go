@@ -0,0 +1,135 @@
+package ordmap
+
+import (
+	"cmp"
+	"iter"
+	"maps"
+)
+
+// MapKey is a constraint for keys that can be used both in a Node and in a Go map.
+type MapKey[K any] interface {
+	Comparable[K]
+	comparable
+}
+
+// FromMap builds a map with the same entries as m.
+// The entries are copied into a slice and sorted, then the tree is built once.
+// Runs in O(N log N).
+func FromMap[K MapKey[K], V any](m map[K]V) *Node[K, V] {
+	entries := make([]Entry[K, V], 0, len(m))
+	for k, v := range m {
+		entries = append(entries, Entry[K, V]{k, v})
+	}
+	return fromEntries(entries)
+}
+
+// ToMap returns a Go map with the same entries as node.
+// This is a function rather than a method since Node keys need not be comparable.
+func ToMap[K MapKey[K], V any](node *Node[K, V]) map[K]V {
+	m := make(map[K]V, node.Len())
+	for k, v := range node.All() {
+		m[k] = v
+	}
+	return m
+}
+
+// Collect builds a map from a sequence in arbitrary order, mirroring maps.Collect.
+// If a key occurs multiple times, the last value wins.
+// See FromSeq for details.
+func Collect[K Comparable[K], V any](seq iter.Seq2[K, V]) *Node[K, V] {
+	return FromSeq(seq)
+}
+
+// InsertSeq inserts all key-value pairs from seq, mirroring maps.Insert.
+// If a key occurs multiple times, the last value wins.
+// Returns a new map containing the changes.
+func (node *Node[K, V]) InsertSeq(seq iter.Seq2[K, V]) *Node[K, V] {
+	b := node.Builder()
+	for k, v := range seq {
+		b.Insert(k, v)
+	}
+	return b.Freeze()
+}
+
+// FromMapBuiltin builds a map with the same entries as m.
+// See FromMap for details.
+func FromMapBuiltin[K BuiltinComparable, V any](m map[K]V) NodeBuiltin[K, V] {
+	return FromSeqBuiltin(maps.All(m))
+}
+
+// ToMap returns a Go map with the same entries as the map.
+func (n NodeBuiltin[K, V]) ToMap() map[K]V {
+	m := make(map[K]V, n.Len())
+	for k, v := range n.n.All() {
+		m[k.value] = v
+	}
+	return m
+}
+
+// CollectBuiltin builds a map from a sequence in arbitrary order, mirroring maps.Collect.
+// See Collect for details.
+func CollectBuiltin[K BuiltinComparable, V any](seq iter.Seq2[K, V]) NodeBuiltin[K, V] {
+	return FromSeqBuiltin(seq)
+}
+
+// InsertSeq inserts all key-value pairs from seq, mirroring maps.Insert.
+// If a key occurs multiple times, the last value wins.
+// Returns a new map containing the changes.
+func (n NodeBuiltin[K, V]) InsertSeq(seq iter.Seq2[K, V]) NodeBuiltin[K, V] {
+	return NodeBuiltin[K, V]{n.n.InsertSeq(wrapSeq(seq))}
+}
+
+// FromMapOrdered builds a map with the same entries as m.
+// See FromMap for details.
+func FromMapOrdered[K cmp.Ordered, V any](m map[K]V) *NodeOrdered[K, V] {
+	return FromSeqOrdered(maps.All(m))
+}
+
+// ToMap returns a Go map with the same entries as the map.
+func (node *NodeOrdered[K, V]) ToMap() map[K]V {
+	m := make(map[K]V, node.Len())
+	for k, v := range node.All() {
+		m[k] = v
+	}
+	return m
+}
+
+// CollectOrdered builds a map from a sequence in arbitrary order, mirroring maps.Collect.
+// See Collect for details.
+func CollectOrdered[K cmp.Ordered, V any](seq iter.Seq2[K, V]) *NodeOrdered[K, V] {
+	return FromSeqOrdered(seq)
+}
+
+// InsertSeq inserts all key-value pairs from seq, mirroring maps.Insert.
+// If a key occurs multiple times, the last value wins.
+// Returns a new map containing the changes.
+func (node *NodeOrdered[K, V]) InsertSeq(seq iter.Seq2[K, V]) *NodeOrdered[K, V] {
+	b := node.Builder()
+	for k, v := range seq {
+		b.Insert(k, v)
+	}
+	return b.Freeze()
+}
+
+// InsertSeq inserts all key-value pairs from seq, mirroring maps.Insert.
+// If a key occurs multiple times, the last value wins.
+// Returns a new map containing the changes.
+func (n NodeFunc[K, V]) InsertSeq(seq iter.Seq2[K, V]) NodeFunc[K, V] {
+	b := n.Builder()
+	for k, v := range seq {
+		b.Insert(k, v)
+	}
+	return b.Freeze()
+}
+
+// InsertSeq inserts all key-value pairs from seq, mirroring maps.Insert.
+// If a key occurs multiple times, the last value wins.
+// Every inserted value is hashed, exactly as by Insert.
+// Returns a new map containing the changes.
+func (m NodeMerkle[K, V]) InsertSeq(seq iter.Seq2[K, V]) NodeMerkle[K, V] {
+	b := m.Builder()
+	for k, v := range seq {
+		b.Insert(k, v)
+	}
+	return b.Freeze()
+}
@@ -0,0 +1,89 @@
+package ordmap
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromMapToMap(t *testing.T) {
+	tree, model := randomTree(200, 500)
+	wrapped := make(map[Builtin[int]]int, len(model))
+	for k, v := range model {
+		wrapped[Builtin[int]{k}] = v
+	}
+
+	fromMap := FromMap(wrapped)
+	validateHeight(t, fromMap)
+	validateOrdered(t, fromMap)
+	require.Equal(t, tree.Entries(), fromMap.Entries())
+	require.Equal(t, wrapped, ToMap(tree))
+
+	builtin := FromMapBuiltin(model)
+	require.Equal(t, unwrapEntries(tree.Entries()), builtin.Entries())
+	require.Equal(t, model, builtin.ToMap())
+
+	ordered := FromMapOrdered(model)
+	validateNodeOrdered(t, ordered)
+	require.Equal(t, unwrapEntries(tree.Entries()), ordered.Entries())
+	require.Equal(t, model, ordered.ToMap())
+
+	require.Nil(t, FromMap(map[Builtin[int]]int{}))
+	require.Empty(t, ToMap[Builtin[int], int](nil))
+}
+
+func TestCollect(t *testing.T) {
+	seq := func(yield func(int, string) bool) {
+		for _, k := range []int{3, 1, 2, 1} {
+			if !yield(k, string(rune('a'+k))) {
+				return
+			}
+		}
+	}
+	// composes with the iterator functions in the standard library
+	builtin := CollectBuiltin(maps.All(map[int]string{1: "b", 2: "c", 3: "d"}))
+	require.Equal(t, []int{1, 2, 3}, builtin.KeysSlice())
+	require.Equal(t, []int{1, 2, 3}, CollectBuiltin(seq).KeysSlice())
+	require.Equal(t, []int{1, 2, 3}, CollectOrdered(seq).KeysSlice())
+	require.Equal(t, []string{"b", "c", "d"}, slices.Collect(Collect(wrapSeq(seq)).Values()))
+	require.Equal(t, []int{3, 1, 2, 1}, slices.Collect(keysOf(seq)))
+	require.Equal(t, []string{"d", "b", "c", "b"}, slices.Collect(valuesOf(seq)))
+}
+
+func TestInsertSeq(t *testing.T) {
+	tree, model := randomTree(200, 500)
+	extra, extraModel := randomTree(200, 500)
+	inserted := tree.InsertSeq(extra.All())
+	validateHeight(t, inserted)
+	validateOrdered(t, inserted)
+	maps.Copy(model, extraModel)
+	require.Equal(t, modelEntries(model), inserted.Entries())
+	require.Same(t, tree, tree.InsertSeq(maps.All(map[Builtin[int]]int{})))
+
+	builtin := NewBuiltin[int, string]().Insert(1, "a")
+	builtin = builtin.InsertSeq(maps.All(map[int]string{1: "b", 2: "c"}))
+	require.Equal(t, map[int]string{1: "b", 2: "c"}, builtin.ToMap())
+
+	ordered := NewOrdered[int, string]().Insert(1, "a")
+	ordered = ordered.InsertSeq(maps.All(map[int]string{1: "b", 2: "c"}))
+	validateNodeOrdered(t, ordered)
+	require.Equal(t, map[int]string{1: "b", 2: "c"}, ordered.ToMap())
+
+	fn := NewFunc[int, string](cmp.Compare[int]).Insert(1, "a")
+	fn = fn.InsertSeq(maps.All(map[int]string{1: "b", 2: "c"}))
+	require.Equal(t, []Entry[int, string]{{1, "b"}, {2, "c"}}, fn.Entries())
+
+	merkle, _ := randomMerkle(200, 500)
+	extraMerkle, _ := randomMerkle(200, 500)
+	want := merkle
+	for k, v := range extraMerkle.All() {
+		want = want.Insert(k, v)
+	}
+	insertedMerkle := merkle.InsertSeq(extraMerkle.All())
+	validateMerkle(t, insertedMerkle)
+	require.Equal(t, want.Entries(), insertedMerkle.Entries())
+	require.Equal(t, want.RootHash(), insertedMerkle.RootHash())
+}
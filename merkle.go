@@ -0,0 +1,616 @@
+package ordmap
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"iter"
+	"math/bits"
+	"slices"
+)
+
+// Hash is a 256-bit digest of an entry or of a set of entries.
+type Hash [32]byte
+
+// add and sub treat hashes as little endian 256-bit integers, wrapping around on overflow.
+func (h Hash) add(other Hash) Hash {
+	var sum Hash
+	var carry uint64
+	for i := 0; i < len(h); i += 8 {
+		var limb uint64
+		limb, carry = bits.Add64(binary.LittleEndian.Uint64(h[i:]), binary.LittleEndian.Uint64(other[i:]), carry)
+		binary.LittleEndian.PutUint64(sum[i:], limb)
+	}
+	return sum
+}
+
+func (h Hash) sub(other Hash) Hash {
+	var diff Hash
+	var borrow uint64
+	for i := 0; i < len(h); i += 8 {
+		var limb uint64
+		limb, borrow = bits.Sub64(binary.LittleEndian.Uint64(h[i:]), binary.LittleEndian.Uint64(other[i:]), borrow)
+		binary.LittleEndian.PutUint64(diff[i:], limb)
+	}
+	return diff
+}
+
+// Hasher computes the hash of a single entry of a NodeMerkle.
+// It should behave like a cryptographic hash of both the key and the value.
+type Hasher[K, V any] interface {
+	Hash(key K, value V) Hash
+}
+
+// SHA256Hasher hashes entries with SHA-256 of their encodings.
+// Its codecs must be able to encode every entry stored in the map, see Hash.
+type SHA256Hasher[K, V any] struct {
+	Keys   Codec[K]
+	Values Codec[V]
+}
+
+// Hash returns the SHA-256 digest of the encodings of key and value.
+// Since a Hasher has no way to report an error, Hash panics if one of the codecs fails,
+// and so do the NodeMerkle operations storing the entry.
+func (h SHA256Hasher[K, V]) Hash(key K, value V) Hash {
+	buf, scratch, err := appendRecord(nil, nil, h.Keys, key)
+	if err == nil {
+		buf, _, err = appendRecord(buf, scratch, h.Values, value)
+	}
+	if err != nil {
+		panic(err)
+	}
+	return sha256.Sum256(buf)
+}
+
+// KeyRange describes the keys between Lo and Hi.
+// Opts controls whether each end is inclusive, exclusive or unbounded, same as for Range.
+type KeyRange[K any] struct {
+	Lo, Hi K
+	Opts   RangeOptions
+}
+
+// Fingerprint summarizes the entries in a key range.
+type Fingerprint struct {
+	Hash Hash
+	Len  int
+}
+
+// Peer answers the questions Reconcile asks about another map, typically over the network.
+// A NodeMerkle can serve as a Peer itself.
+type Peer[K any] interface {
+	// Fingerprints returns the fingerprint of each of the ranges.
+	Fingerprints(ranges []KeyRange[K]) ([]Fingerprint, error)
+}
+
+// reconcileLeafSize is the number of entries below which Reconcile reports a differing
+// range as is instead of narrowing it down further, since sending a few entries
+// costs about as much as another round of fingerprints.
+const reconcileLeafSize = 8
+
+// NodeMerkle is a persistent ordered map that keeps a hash of every subtree,
+// allowing maps to be compared and synchronized without exchanging their entries.
+// Besides that it has the same API as Node.
+//
+// The hash of a subtree is the sum of the hashes of its entries (as 256-bit integers).
+// This makes it independent of the shape of the tree, so maps with the same entries
+// have the same RootHash and the hash of any key range can be computed in O(log N),
+// no matter how the maps were built. Unlike a hash chain, a sum is not collision
+// resistant against an adversary who controls many of the entries.
+//
+// Every entry stored in the map is hashed once by the Hasher, panics included.
+// Operations combining two maps (Union, Intersection, Difference, Join) require
+// both of them to use the same Hasher.
+//
+// The zero value is not usable, create maps with NewMerkle.
+type NodeMerkle[K Comparable[K], V any] struct {
+	hasher Hasher[K, V]
+	root   *tree[K, hashed[V], Hash]
+}
+
+// hashed is a value of a NodeMerkle stored along with the hash of its entry,
+// so that rebuilding a node while rebalancing does not need to hash it again.
+type hashed[V any] struct {
+	value V
+	hash  Hash
+}
+
+// merkleCore returns the algorithms on the trees of NodeMerkle, which are augmented
+// with the sum of the hashes of the entries in each subtree.
+func merkleCore[K Comparable[K], V any]() core[K, hashed[V], Hash, lessOrder[K, hashed[V], Hash]] {
+	return core[K, hashed[V], Hash, lessOrder[K, hashed[V], Hash]]{augment: sumEntries[K, V]}
+}
+
+func sumEntries[K, V any](entry Entry[K, hashed[V]], left, right *tree[K, hashed[V], Hash]) Hash {
+	return sumOf(left).add(entry.V.hash).add(sumOf(right))
+}
+
+func sumOf[K, V any](node *tree[K, hashed[V], Hash]) Hash {
+	if node == nil {
+		return Hash{}
+	}
+	return node.aug
+}
+
+// NewMerkle returns an empty NodeMerkle (map) hashing entries with hasher.
+func NewMerkle[K Comparable[K], V any](hasher Hasher[K, V]) NodeMerkle[K, V] {
+	return NodeMerkle[K, V]{hasher: hasher}
+}
+
+// FromSortedMerkle builds a perfectly balanced map from entries sorted by strictly ascending key.
+// See FromSorted for details.
+func FromSortedMerkle[K Comparable[K], V any](hasher Hasher[K, V], entries []Entry[K, V]) NodeMerkle[K, V] {
+	m := NewMerkle(hasher)
+	return m.with(merkleCore[K, V]().fromSorted(m.hashEntries(entries)))
+}
+
+// FromSortedSeqMerkle builds a perfectly balanced map from a sequence sorted by strictly ascending key.
+// See FromSortedSeq for details.
+func FromSortedSeqMerkle[K Comparable[K], V any](hasher Hasher[K, V], seq iter.Seq2[K, V]) NodeMerkle[K, V] {
+	var entries []Entry[K, V]
+	for k, v := range seq {
+		entries = append(entries, Entry[K, V]{k, v})
+	}
+	return FromSortedMerkle(hasher, entries)
+}
+
+// FromSeqMerkle builds a map from a sequence in arbitrary order.
+// See FromSeq for details.
+func FromSeqMerkle[K Comparable[K], V any](hasher Hasher[K, V], seq iter.Seq2[K, V]) NodeMerkle[K, V] {
+	var entries []Entry[K, V]
+	for k, v := range seq {
+		entries = append(entries, Entry[K, V]{k, v})
+	}
+	m := NewMerkle(hasher)
+	return m.with(merkleCore[K, V]().fromEntries(m.hashEntries(entries)))
+}
+
+func (m NodeMerkle[K, V]) with(root *tree[K, hashed[V], Hash]) NodeMerkle[K, V] {
+	return NodeMerkle[K, V]{m.hasher, root}
+}
+
+func (m NodeMerkle[K, V]) hash(key K, value V) hashed[V] {
+	return hashed[V]{value, m.hasher.Hash(key, value)}
+}
+
+func (m NodeMerkle[K, V]) hashEntries(entries []Entry[K, V]) []Entry[K, hashed[V]] {
+	hashedEntries := make([]Entry[K, hashed[V]], len(entries))
+	for i, e := range entries {
+		hashedEntries[i] = Entry[K, hashed[V]]{e.K, m.hash(e.K, e.V)}
+	}
+	return hashedEntries
+}
+
+// rehashResolve wraps resolve so that the values it produces are hashed.
+// A nil resolve stays nil, as then the values are taken over along with their hashes.
+func (m NodeMerkle[K, V]) rehashResolve(resolve func(k K, va, vb V) V) func(K, hashed[V], hashed[V]) hashed[V] {
+	if resolve == nil {
+		return nil
+	}
+	return func(k K, va, vb hashed[V]) hashed[V] {
+		return m.hash(k, resolve(k, va.value, vb.value))
+	}
+}
+
+func unhashEntry[K, V any](e *Entry[K, hashed[V]]) *Entry[K, V] {
+	if e == nil {
+		return nil
+	}
+	return &Entry[K, V]{e.K, e.V.value}
+}
+
+func unhashSeq[K, V any](seq iter.Seq2[K, hashed[V]]) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for k, v := range seq {
+			if !yield(k, v.value) {
+				return
+			}
+		}
+	}
+}
+
+// Get retrieves the value for the given key.
+// It returns the value and true if the key exists, otherwise the zero value and false.
+func (m NodeMerkle[K, V]) Get(key K) (value V, ok bool) {
+	v, ok := merkleCore[K, V]().get(m.root, key)
+	return v.value, ok
+}
+
+// Insert adds a key-value pair to the map.
+// If the key already exists, its value is updated, even if the new entry has the same hash.
+// Returns a new map containing the change.
+func (m NodeMerkle[K, V]) Insert(key K, value V) NodeMerkle[K, V] {
+	return m.with(merkleCore[K, V]().insert(m.root, key, m.hash(key, value), nil))
+}
+
+// InsertFunc adds a key-value pair to the map like Insert, but if the key already
+// exists and eq reports the old and new values as equal, the map is returned unchanged.
+func (m NodeMerkle[K, V]) InsertFunc(key K, value V, eq func(old, new V) bool) NodeMerkle[K, V] {
+	return m.with(merkleCore[K, V]().insert(m.root, key, m.hash(key, value), func(old, new hashed[V]) bool {
+		return eq(old.value, new.value)
+	}))
+}
+
+// Update inserts, modifies or deletes the entry for key in a single descent.
+// See Node.Update for details.
+func (m NodeMerkle[K, V]) Update(key K, fn func(old V, exists bool) (V, bool)) NodeMerkle[K, V] {
+	return m.with(merkleCore[K, V]().update(m.root, key, func(old hashed[V], exists bool) (hashed[V], bool) {
+		value, keep := fn(old.value, exists)
+		if !keep {
+			return hashed[V]{}, false
+		}
+		return m.hash(key, value), true
+	}))
+}
+
+// Remove deletes the key from the map.
+// If the key does not exist, the map is returned unchanged.
+// Returns a new map containing the change.
+func (m NodeMerkle[K, V]) Remove(key K) NodeMerkle[K, V] {
+	return m.with(merkleCore[K, V]().remove(m.root, key))
+}
+
+// Split partitions the map around key.
+// See Node.Split for details.
+func (m NodeMerkle[K, V]) Split(key K) (left NodeMerkle[K, V], value V, found bool, right NodeMerkle[K, V]) {
+	l, v, found, r := merkleCore[K, V]().split(m.root, key)
+	return m.with(l), v.value, found, m.with(r)
+}
+
+// Join concatenates two maps where all keys in m are less than all keys in right.
+// See Join for details.
+func (m NodeMerkle[K, V]) Join(right NodeMerkle[K, V]) NodeMerkle[K, V] {
+	return m.with(merkleCore[K, V]().concat(m.root, right.root))
+}
+
+// Union returns a map containing all keys from m and b.
+// Values produced by resolve are hashed with the hasher of m.
+// See Union for details.
+func (m NodeMerkle[K, V]) Union(b NodeMerkle[K, V], resolve func(k K, va, vb V) V) NodeMerkle[K, V] {
+	return m.with(merkleCore[K, V]().union(m.root, b.root, m.rehashResolve(resolve)))
+}
+
+// Intersection returns a map containing only keys present in both m and b.
+// Values produced by resolve are hashed with the hasher of m.
+// See Intersection for details.
+func (m NodeMerkle[K, V]) Intersection(b NodeMerkle[K, V], resolve func(k K, va, vb V) V) NodeMerkle[K, V] {
+	return m.with(merkleCore[K, V]().intersection(m.root, b.root, m.rehashResolve(resolve)))
+}
+
+// Difference returns a map containing the entries of m whose keys are not present in b.
+// See Difference for details.
+func (m NodeMerkle[K, V]) Difference(b NodeMerkle[K, V]) NodeMerkle[K, V] {
+	return m.with(merkleCore[K, V]().difference(m.root, b.root))
+}
+
+// RemoveRange deletes all keys between lo and hi.
+// See Node.RemoveRange for details.
+func (m NodeMerkle[K, V]) RemoveRange(lo, hi K, opts RangeOptions) NodeMerkle[K, V] {
+	return m.with(merkleCore[K, V]().removeRange(m.root, lo, hi, opts))
+}
+
+// TruncateBefore deletes all keys strictly less than key.
+func (m NodeMerkle[K, V]) TruncateBefore(key K) NodeMerkle[K, V] {
+	return m.with(merkleCore[K, V]().keepAbove(m.root, key, true))
+}
+
+// TruncateAfter deletes all keys strictly greater than key.
+func (m NodeMerkle[K, V]) TruncateAfter(key K) NodeMerkle[K, V] {
+	return m.with(merkleCore[K, V]().keepBelow(m.root, key, true))
+}
+
+// Len returns the number of elements in the map.
+func (m NodeMerkle[K, V]) Len() int {
+	return m.root.length()
+}
+
+// Entries returns a slice of all key-value pairs in the map, sorted by key.
+func (m NodeMerkle[K, V]) Entries() []Entry[K, V] {
+	entries := make([]Entry[K, V], 0, m.Len())
+	for k, v := range m.All() {
+		entries = append(entries, Entry[K, V]{k, v})
+	}
+	return entries
+}
+
+// Min returns the entry with the smallest key in the map.
+// Returns nil if the map is empty.
+func (m NodeMerkle[K, V]) Min() *Entry[K, V] {
+	return unhashEntry(m.root.extreme(0))
+}
+
+// Max returns the entry with the largest key in the map.
+// Returns nil if the map is empty.
+func (m NodeMerkle[K, V]) Max() *Entry[K, V] {
+	return unhashEntry(m.root.extreme(1))
+}
+
+// PopMin removes the entry with the smallest key in a single descent.
+// Returns the removed entry and the new map, or nil and the unchanged map if it is empty.
+func (m NodeMerkle[K, V]) PopMin() (*Entry[K, V], NodeMerkle[K, V]) {
+	min, rest := merkleCore[K, V]().popExtreme(m.root, 0)
+	return unhashEntry(min), m.with(rest)
+}
+
+// PopMax removes the entry with the largest key in a single descent.
+// Returns the removed entry and the new map, or nil and the unchanged map if it is empty.
+func (m NodeMerkle[K, V]) PopMax() (*Entry[K, V], NodeMerkle[K, V]) {
+	max, rest := merkleCore[K, V]().popExtreme(m.root, 1)
+	return unhashEntry(max), m.with(rest)
+}
+
+// DeleteMin removes the entry with the smallest key.
+// Returns a new map containing the change.
+func (m NodeMerkle[K, V]) DeleteMin() NodeMerkle[K, V] {
+	_, rest := m.PopMin()
+	return rest
+}
+
+// DeleteMax removes the entry with the largest key.
+// Returns a new map containing the change.
+func (m NodeMerkle[K, V]) DeleteMax() NodeMerkle[K, V] {
+	_, rest := m.PopMax()
+	return rest
+}
+
+// Floor returns the entry with the greatest key less than or equal to key.
+// Returns nil if there is no such entry.
+func (m NodeMerkle[K, V]) Floor(key K) *Entry[K, V] {
+	return unhashEntry(merkleCore[K, V]().neighbor(m.root, key, 0, true))
+}
+
+// Ceiling returns the entry with the least key greater than or equal to key.
+// Returns nil if there is no such entry.
+func (m NodeMerkle[K, V]) Ceiling(key K) *Entry[K, V] {
+	return unhashEntry(merkleCore[K, V]().neighbor(m.root, key, 1, true))
+}
+
+// Lower returns the entry with the greatest key strictly less than key.
+// Returns nil if there is no such entry.
+func (m NodeMerkle[K, V]) Lower(key K) *Entry[K, V] {
+	return unhashEntry(merkleCore[K, V]().neighbor(m.root, key, 0, false))
+}
+
+// Higher returns the entry with the least key strictly greater than key.
+// Returns nil if there is no such entry.
+func (m NodeMerkle[K, V]) Higher(key K) *Entry[K, V] {
+	return unhashEntry(merkleCore[K, V]().neighbor(m.root, key, 1, false))
+}
+
+// At returns the entry at position i in key order (0-based).
+// Returns nil if i is out of range.
+func (m NodeMerkle[K, V]) At(i int) *Entry[K, V] {
+	return unhashEntry(m.root.at(i))
+}
+
+// IndexOf returns the position of key in key order (0-based) and true if the key exists,
+// otherwise 0 and false.
+func (m NodeMerkle[K, V]) IndexOf(key K) (int, bool) {
+	return merkleCore[K, V]().indexOf(m.root, key)
+}
+
+// Rank returns the number of keys in the map that are strictly less than key.
+func (m NodeMerkle[K, V]) Rank(key K) int {
+	return merkleCore[K, V]().rank(m.root, key)
+}
+
+// All returns an iterator over all key-value pairs in the map, sorted by key (ascending).
+func (m NodeMerkle[K, V]) All() iter.Seq2[K, V] {
+	return unhashSeq(m.root.all(1))
+}
+
+// Backward returns an iterator over all key-value pairs in the map, sorted by key (descending).
+func (m NodeMerkle[K, V]) Backward() iter.Seq2[K, V] {
+	return unhashSeq(m.root.all(0))
+}
+
+// From returns an iterator over key-value pairs starting from the first key >= k.
+// The iteration proceeds in ascending order.
+func (m NodeMerkle[K, V]) From(k K) iter.Seq2[K, V] {
+	return unhashSeq(merkleCore[K, V]().from(m.root, k, 1))
+}
+
+// BackwardFrom returns an iterator over key-value pairs starting from the first key <= k.
+// The iteration proceeds in descending order.
+func (m NodeMerkle[K, V]) BackwardFrom(k K) iter.Seq2[K, V] {
+	return unhashSeq(merkleCore[K, V]().from(m.root, k, 0))
+}
+
+// Range returns an iterator over key-value pairs with keys between lo and hi.
+// opts controls whether each end is inclusive, exclusive or unbounded.
+// The iteration proceeds in ascending order.
+func (m NodeMerkle[K, V]) Range(lo, hi K, opts RangeOptions) iter.Seq2[K, V] {
+	return unhashSeq(merkleCore[K, V]().within(m.root, lo, hi, opts, 1))
+}
+
+// BackwardRange returns an iterator over key-value pairs with keys between lo and hi.
+// opts controls whether each end is inclusive, exclusive or unbounded.
+// The iteration proceeds in descending order.
+func (m NodeMerkle[K, V]) BackwardRange(lo, hi K, opts RangeOptions) iter.Seq2[K, V] {
+	return unhashSeq(merkleCore[K, V]().within(m.root, lo, hi, opts, 0))
+}
+
+// Keys returns an iterator over all keys in the map in ascending order.
+func (m NodeMerkle[K, V]) Keys() iter.Seq[K] {
+	return m.root.keys()
+}
+
+// Values returns an iterator over all values in the map in ascending order of their keys.
+func (m NodeMerkle[K, V]) Values() iter.Seq[V] {
+	return valuesOf(m.All())
+}
+
+// KeysSlice returns a slice of all keys in the map in ascending order.
+func (m NodeMerkle[K, V]) KeysSlice() []K {
+	return slices.AppendSeq(make([]K, 0, m.Len()), m.Keys())
+}
+
+// ValuesSlice returns a slice of all values in the map in ascending order of their keys.
+func (m NodeMerkle[K, V]) ValuesSlice() []V {
+	return slices.AppendSeq(make([]V, 0, m.Len()), m.Values())
+}
+
+// KeysRange returns an iterator over the keys between lo and hi in ascending order.
+// See Range for details.
+func (m NodeMerkle[K, V]) KeysRange(lo, hi K, opts RangeOptions) iter.Seq[K] {
+	return keysOf(m.Range(lo, hi, opts))
+}
+
+// ValuesRange returns an iterator over the values of keys between lo and hi
+// in ascending order of their keys.
+// See Range for details.
+func (m NodeMerkle[K, V]) ValuesRange(lo, hi K, opts RangeOptions) iter.Seq[V] {
+	return valuesOf(m.Range(lo, hi, opts))
+}
+
+// RootHash returns the hash of all the entries in the map.
+// Maps with the same entries have the same root hash regardless of how they were built.
+func (m NodeMerkle[K, V]) RootHash() Hash {
+	return sumOf(m.root)
+}
+
+// Fingerprint returns the hash and the number of the entries in r in O(log N).
+func (m NodeMerkle[K, V]) Fingerprint(r KeyRange[K]) Fingerprint {
+	sum, n := sumOf(m.root), m.root.length()
+	if r.Opts.Hi != Unbounded {
+		sum, n = m.below(r.Hi, r.Opts.Hi == Inclusive)
+	}
+	if r.Opts.Lo != Unbounded {
+		below, belowN := m.below(r.Lo, r.Opts.Lo == Exclusive)
+		if belowN >= n {
+			return Fingerprint{} // empty range
+		}
+		sum, n = sum.sub(below), n-belowN
+	}
+	return Fingerprint{sum, n}
+}
+
+// Fingerprints returns the fingerprint of each of the ranges, making the map a Peer.
+func (m NodeMerkle[K, V]) Fingerprints(ranges []KeyRange[K]) ([]Fingerprint, error) {
+	fingerprints := make([]Fingerprint, len(ranges))
+	for i, r := range ranges {
+		fingerprints[i] = m.Fingerprint(r)
+	}
+	return fingerprints, nil
+}
+
+// Reconcile returns an iterator over the key ranges where m and the map behind peer differ.
+// Starting from the whole map, ranges whose fingerprints do not match are split in two at
+// a key of m and compared again, until they hold only a few entries of m
+// (or none of the peer, as then all of them differ anyway). Each round
+// asks peer for all the fingerprints of that round at once, so there are O(log N)
+// round trips and O(D log N) fingerprints exchanged for D differences.
+//
+// The reported ranges are disjoint and together cover all the differences,
+// but come in no particular order. Synchronizing them, e.g. by fetching the entries
+// of the peer in each range, is up to the caller. If peer fails, the error is
+// yielded and the iteration stops.
+func (m NodeMerkle[K, V]) Reconcile(peer Peer[K]) iter.Seq2[KeyRange[K], error] {
+	return func(yield func(KeyRange[K], error) bool) {
+		round := []KeyRange[K]{{Opts: RangeOptions{Lo: Unbounded, Hi: Unbounded}}}
+		for len(round) > 0 {
+			remote, err := peer.Fingerprints(round)
+			if err == nil && len(remote) != len(round) {
+				err = errors.New("ordmap: peer returned the wrong number of fingerprints")
+			}
+			if err != nil {
+				yield(KeyRange[K]{}, err)
+				return
+			}
+			var next []KeyRange[K]
+			for i, r := range round {
+				local := m.Fingerprint(r)
+				if local == remote[i] {
+					continue
+				}
+				if local.Len <= reconcileLeafSize || remote[i].Len == 0 {
+					if !yield(r, nil) {
+						return
+					}
+					continue
+				}
+				lower, upper := m.halve(r, local.Len)
+				next = append(next, lower, upper)
+			}
+			round = next
+		}
+	}
+}
+
+// halve splits r, holding n > 1 entries of m, in two at its middle key.
+func (m NodeMerkle[K, V]) halve(r KeyRange[K], n int) (lower, upper KeyRange[K]) {
+	first := 0
+	if r.Opts.Lo != Unbounded {
+		_, first = m.below(r.Lo, r.Opts.Lo == Exclusive)
+	}
+	mid := m.root.at(first + n/2).K
+	lower, upper = r, r
+	lower.Hi, lower.Opts.Hi = mid, Exclusive
+	upper.Lo, upper.Opts.Lo = mid, Inclusive
+	return lower, upper
+}
+
+// below returns the sum and the number of entries with keys less than key,
+// or less than or equal to key if inclusive is set.
+func (m NodeMerkle[K, V]) below(key K, inclusive bool) (sum Hash, n int) {
+	c := merkleCore[K, V]()
+	finger := m.root
+	for finger != nil {
+		d := c.ord.compare(finger.entry.K, key)
+		if d < 0 || inclusive && d == 0 {
+			sum = sum.add(sumOf(finger.children[0])).add(finger.entry.V.hash)
+			n += finger.children[0].length() + 1
+			finger = finger.children[1]
+		} else {
+			finger = finger.children[0]
+		}
+	}
+	return sum, n
+}
+
+// CursorMerkle is a bidirectional iterator over a NodeMerkle.
+// See Cursor for details.
+type CursorMerkle[K Comparable[K], V any] struct {
+	cursor[K, hashed[V], Hash, lessOrder[K, hashed[V], Hash]]
+}
+
+// Cursor returns a new unpositioned cursor over the map.
+func (m NodeMerkle[K, V]) Cursor() *CursorMerkle[K, V] {
+	return &CursorMerkle[K, V]{newCursor(merkleCore[K, V](), m.root)}
+}
+
+// Value returns the value at the current position, or the zero value if the cursor is not valid.
+func (c *CursorMerkle[K, V]) Value() V {
+	return c.cursor.Value().value
+}
+
+// BuilderMerkle is a mutable (transient) view of a NodeMerkle used for efficient batch construction.
+// See Builder for details.
+type BuilderMerkle[K Comparable[K], V any] struct {
+	builder[K, hashed[V], Hash, lessOrder[K, hashed[V], Hash]]
+	hasher Hasher[K, V]
+}
+
+// Builder returns a BuilderMerkle starting out with the contents of the map.
+// The map itself is never modified.
+func (m NodeMerkle[K, V]) Builder() *BuilderMerkle[K, V] {
+	return &BuilderMerkle[K, V]{newBuilder(merkleCore[K, V](), m.root), m.hasher}
+}
+
+// Get retrieves the value for the given key.
+// It returns the value and true if the key exists, otherwise the zero value and false.
+func (b *BuilderMerkle[K, V]) Get(key K) (value V, ok bool) {
+	v, ok := b.builder.Get(key)
+	return v.value, ok
+}
+
+// Insert adds a key-value pair.
+// If the key already exists, its value is updated.
+func (b *BuilderMerkle[K, V]) Insert(key K, value V) {
+	b.builder.Insert(key, hashed[V]{value, b.hasher.Hash(key, value)})
+}
+
+// Freeze returns the current contents of the builder as a persistent map.
+// See Builder.Freeze for details.
+func (b *BuilderMerkle[K, V]) Freeze() NodeMerkle[K, V] {
+	return NodeMerkle[K, V]{b.hasher, b.freeze()}
+}
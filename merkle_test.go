@@ -0,0 +1,390 @@
+package ordmap
+
+import (
+	"errors"
+	"iter"
+	"maps"
+	"math/bits"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var merkleHasher = SHA256Hasher[Builtin[int], int]{intCodec, IntCodec[int]{}}
+
+func validateMerkle[K Comparable[K], V any](t *testing.T, m NodeMerkle[K, V]) {
+	validateTree(t, merkleCore[K, V](), m.root)
+	for _, e := range m.root.entries() {
+		require.Equal(t, m.hasher.Hash(e.K, e.V.value), e.V.hash)
+	}
+}
+
+func randomMerkle(n, keyRange int) (NodeMerkle[Builtin[int], int], map[int]int) {
+	m := NewMerkle(merkleHasher)
+	model := map[int]int{}
+	for range n {
+		k, v := rand.Intn(keyRange), rand.Intn(10)
+		m = m.Insert(Builtin[int]{k}, v)
+		model[k] = v
+	}
+	return m, model
+}
+
+func TestMerkle(t *testing.T) {
+	m, model := randomMerkle(1000, 2000)
+	for range 1000 {
+		k := rand.Intn(2000)
+		if rand.Intn(2) == 0 {
+			m = m.Remove(Builtin[int]{k})
+			delete(model, k)
+		} else {
+			m = m.Insert(Builtin[int]{k}, k)
+			model[k] = k
+		}
+	}
+	validateMerkle(t, m)
+	require.Equal(t, modelEntries(model), m.Entries())
+	for k, v := range model {
+		got, ok := m.Get(Builtin[int]{k})
+		require.True(t, ok)
+		require.Equal(t, v, got)
+	}
+	_, ok := m.Get(Builtin[int]{-1})
+	require.False(t, ok)
+
+	// same entries, same hash, however the map was built
+	rebuilt := NewMerkle(merkleHasher)
+	for _, e := range slices.Backward(m.Entries()) {
+		rebuilt = rebuilt.Insert(e.K, e.V)
+	}
+	require.Equal(t, m.RootHash(), rebuilt.RootHash())
+
+	first := m.Entries()[0]
+	changed := m.Insert(first.K, -1)
+	require.NotEqual(t, m.RootHash(), changed.RootHash())
+	require.Equal(t, m.RootHash(), changed.Insert(first.K, first.V).RootHash())
+	require.NotEqual(t, m.RootHash(), m.Remove(first.K).RootHash())
+
+	// unchanged maps keep their root
+	require.Same(t, m.root, m.InsertFunc(first.K, first.V, func(old, new int) bool { return old == new }).root)
+	require.Same(t, m.root, m.Remove(Builtin[int]{-1}).root)
+	require.Equal(t, Hash{}, NewMerkle(merkleHasher).RootHash())
+}
+
+// keyHasher hashes only the keys, so entries differing in their values have the same hash.
+type keyHasher struct{}
+
+func (keyHasher) Hash(key Builtin[int], value int) Hash {
+	return merkleHasher.Hash(key, 0)
+}
+
+func TestMerkleKeyOnlyHasher(t *testing.T) {
+	m := NewMerkle[Builtin[int], int](keyHasher{}).Insert(Builtin[int]{1}, 1)
+	changed := m.Insert(Builtin[int]{1}, 2)
+	v, _ := changed.Get(Builtin[int]{1})
+	require.Equal(t, 2, v)
+	require.Equal(t, m.RootHash(), changed.RootHash())
+
+	changed = m.Update(Builtin[int]{1}, func(old int, exists bool) (int, bool) { return old + 2, true })
+	v, _ = changed.Get(Builtin[int]{1})
+	require.Equal(t, 3, v)
+
+	b := m.Builder()
+	b.Insert(Builtin[int]{1}, 4)
+	v, _ = b.Freeze().Get(Builtin[int]{1})
+	require.Equal(t, 4, v)
+}
+
+// TestMerkleAPI checks the operations NodeMerkle shares with Node against a Node.
+func TestMerkleAPI(t *testing.T) {
+	key := func(k int) Builtin[int] { return Builtin[int]{k} }
+	sum := func(k Builtin[int], va, vb int) int { return va + vb }
+	random := func() (NodeMerkle[Builtin[int], int], *Node[Builtin[int], int]) {
+		model := map[Builtin[int]]int{}
+		for range 200 {
+			model[key(rand.Intn(400))] = rand.Intn(10)
+		}
+		return FromSeqMerkle(merkleHasher, maps.All(model)), FromSeq(maps.All(model))
+	}
+	check := func(m NodeMerkle[Builtin[int], int], node *Node[Builtin[int], int]) {
+		t.Helper()
+		validateMerkle(t, m)
+		require.Equal(t, node.Entries(), m.Entries())
+	}
+
+	m, node := random()
+	check(m, node)
+	check(FromSortedMerkle(merkleHasher, node.Entries()), node)
+	check(FromSortedSeqMerkle(merkleHasher, node.All()), node)
+	for range 500 {
+		k, v := rand.Intn(400), rand.Intn(10)
+		switch rand.Intn(4) {
+		case 0:
+			m, node = m.Remove(key(k)), node.Remove(key(k))
+		case 1:
+			fn := func(old int, exists bool) (int, bool) { return old + 1, old%3 != 2 }
+			m, node = m.Update(key(k), fn), node.Update(key(k), fn)
+		default:
+			eq := func(old, new int) bool { return old == new }
+			m, node = m.InsertFunc(key(k), v, eq), node.InsertFunc(key(k), v, eq)
+		}
+	}
+	check(m, node)
+
+	lo, hi := key(100), key(300)
+	opts := RangeOptions{Lo: Exclusive, Hi: Inclusive}
+	left, value, found, right := m.Split(key(200))
+	nodeLeft, nodeValue, nodeFound, nodeRight := node.Split(key(200))
+	check(left, nodeLeft)
+	check(right, nodeRight)
+	require.Equal(t, nodeValue, value)
+	require.Equal(t, nodeFound, found)
+	check(left.Join(right), Join(nodeLeft, nodeRight))
+	check(m.RemoveRange(lo, hi, opts), node.RemoveRange(lo, hi, opts))
+	check(m.TruncateBefore(lo), node.TruncateBefore(lo))
+	check(m.TruncateAfter(hi), node.TruncateAfter(hi))
+	check(m.DeleteMin(), node.DeleteMin())
+	check(m.DeleteMax(), node.DeleteMax())
+	min, rest := m.PopMin()
+	nodeMin, nodeRest := node.PopMin()
+	require.Equal(t, nodeMin, min)
+	check(rest, nodeRest)
+	max, rest := m.PopMax()
+	nodeMax, nodeRest := node.PopMax()
+	require.Equal(t, nodeMax, max)
+	check(rest, nodeRest)
+
+	other, otherNode := random()
+	check(m.Union(other, nil), Union(node, otherNode, nil))
+	check(m.Union(other, sum), Union(node, otherNode, sum))
+	check(m.Intersection(other, nil), Intersection(node, otherNode, nil))
+	check(m.Intersection(other, sum), Intersection(node, otherNode, sum))
+	check(m.Difference(other), Difference(node, otherNode))
+
+	require.Equal(t, node.Min(), m.Min())
+	require.Equal(t, node.Max(), m.Max())
+	for k := -1; k <= 400; k++ {
+		require.Equal(t, node.Floor(key(k)), m.Floor(key(k)))
+		require.Equal(t, node.Ceiling(key(k)), m.Ceiling(key(k)))
+		require.Equal(t, node.Lower(key(k)), m.Lower(key(k)))
+		require.Equal(t, node.Higher(key(k)), m.Higher(key(k)))
+		require.Equal(t, node.At(k), m.At(k))
+		require.Equal(t, node.Rank(key(k)), m.Rank(key(k)))
+		i, ok := m.IndexOf(key(k))
+		nodeI, nodeOK := node.IndexOf(key(k))
+		require.Equal(t, nodeI, i)
+		require.Equal(t, nodeOK, ok)
+	}
+
+	collect := func(seq iter.Seq2[Builtin[int], int]) []Entry[Builtin[int], int] {
+		var entries []Entry[Builtin[int], int]
+		for k, v := range seq {
+			entries = append(entries, Entry[Builtin[int], int]{k, v})
+		}
+		return entries
+	}
+	require.Equal(t, collect(node.Backward()), collect(m.Backward()))
+	require.Equal(t, collect(node.From(lo)), collect(m.From(lo)))
+	require.Equal(t, collect(node.BackwardFrom(hi)), collect(m.BackwardFrom(hi)))
+	require.Equal(t, collect(node.Range(lo, hi, opts)), collect(m.Range(lo, hi, opts)))
+	require.Equal(t, collect(node.BackwardRange(lo, hi, opts)), collect(m.BackwardRange(lo, hi, opts)))
+	require.Equal(t, node.KeysSlice(), m.KeysSlice())
+	require.Equal(t, node.ValuesSlice(), m.ValuesSlice())
+	require.Equal(t, slices.Collect(node.KeysRange(lo, hi, opts)), slices.Collect(m.KeysRange(lo, hi, opts)))
+	require.Equal(t, slices.Collect(node.ValuesRange(lo, hi, opts)), slices.Collect(m.ValuesRange(lo, hi, opts)))
+
+	c, nodeCursor := m.Cursor(), node.Cursor()
+	require.Equal(t, nodeCursor.Seek(lo), c.Seek(lo))
+	for c.Valid() {
+		require.Equal(t, nodeCursor.Key(), c.Key())
+		require.Equal(t, nodeCursor.Value(), c.Value())
+		require.Equal(t, nodeCursor.Next(), c.Next())
+	}
+
+	b, nodeBuilder := m.Builder(), node.Builder()
+	for range 200 {
+		k, v := key(rand.Intn(400)), rand.Intn(10)
+		if rand.Intn(2) == 0 {
+			b.Remove(k)
+			nodeBuilder.Remove(k)
+		} else {
+			b.Insert(k, v)
+			nodeBuilder.Insert(k, v)
+		}
+		got, ok := b.Get(k)
+		expected, expectedOK := nodeBuilder.Get(k)
+		require.Equal(t, expected, got)
+		require.Equal(t, expectedOK, ok)
+	}
+	require.Equal(t, nodeBuilder.Len(), b.Len())
+	check(b.Freeze(), nodeBuilder.Freeze())
+	check(m, node) // untouched by the builder
+}
+
+func TestMerkleFingerprint(t *testing.T) {
+	m, model := randomMerkle(300, 600)
+	c := nodeCore[Builtin[int], int]()
+	for range 1000 {
+		lo, hi := rand.Intn(700)-50, rand.Intn(700)-50
+		opts := RangeOptions{Lo: Bound(rand.Intn(3)), Hi: Bound(rand.Intn(3))}
+		var expected Fingerprint
+		for k, v := range model {
+			if c.aboveLower(Builtin[int]{k}, Builtin[int]{lo}, opts.Lo) && c.belowUpper(Builtin[int]{k}, Builtin[int]{hi}, opts.Hi) {
+				expected.Hash = expected.Hash.add(merkleHasher.Hash(Builtin[int]{k}, v))
+				expected.Len++
+			}
+		}
+		r := KeyRange[Builtin[int]]{Builtin[int]{lo}, Builtin[int]{hi}, opts}
+		require.Equal(t, expected, m.Fingerprint(r), "%v %v %v", lo, hi, opts)
+		var n int
+		for range m.Range(r.Lo, r.Hi, r.Opts) {
+			n++
+		}
+		require.Equal(t, expected.Len, n)
+	}
+	all := KeyRange[Builtin[int]]{Opts: RangeOptions{Lo: Unbounded, Hi: Unbounded}}
+	require.Equal(t, Fingerprint{m.RootHash(), m.Len()}, m.Fingerprint(all))
+	require.Equal(t, Fingerprint{}, NewMerkle(merkleHasher).Fingerprint(all))
+
+	// wrapping around in both directions
+	ones := Hash{}
+	for i := range ones {
+		ones[i] = 0xff
+	}
+	require.Equal(t, Hash{}, ones.add(Hash{1}))
+	require.Equal(t, ones, Hash{}.sub(Hash{1}))
+}
+
+// transport runs a map on a goroutine and answers requests over channels,
+// standing in for a remote replica.
+type transport struct {
+	requests  chan []KeyRange[Builtin[int]]
+	responses chan []Fingerprint
+	fetches   chan KeyRange[Builtin[int]]
+	entries   chan []Entry[Builtin[int], int]
+	rounds    int
+	sent      int
+	fail      bool
+}
+
+func serve(m NodeMerkle[Builtin[int], int]) *transport {
+	t := &transport{
+		requests:  make(chan []KeyRange[Builtin[int]]),
+		responses: make(chan []Fingerprint),
+		fetches:   make(chan KeyRange[Builtin[int]]),
+		entries:   make(chan []Entry[Builtin[int], int]),
+	}
+	go func() {
+		for {
+			select {
+			case ranges, ok := <-t.requests:
+				if !ok {
+					return
+				}
+				fingerprints, _ := m.Fingerprints(ranges)
+				t.responses <- fingerprints
+			case r := <-t.fetches:
+				var entries []Entry[Builtin[int], int]
+				for k, v := range m.Range(r.Lo, r.Hi, r.Opts) {
+					entries = append(entries, Entry[Builtin[int], int]{k, v})
+				}
+				t.entries <- entries
+			}
+		}
+	}()
+	return t
+}
+
+func (t *transport) Fingerprints(ranges []KeyRange[Builtin[int]]) ([]Fingerprint, error) {
+	if t.fail {
+		return nil, errors.New("connection reset")
+	}
+	t.rounds++
+	t.sent += len(ranges)
+	t.requests <- ranges
+	return <-t.responses, nil
+}
+
+func (t *transport) fetch(r KeyRange[Builtin[int]]) []Entry[Builtin[int], int] {
+	t.fetches <- r
+	return <-t.entries
+}
+
+// pull makes local match the remote behind t, fetching only the differing ranges.
+func pull(t *testing.T, local NodeMerkle[Builtin[int], int], remote *transport) NodeMerkle[Builtin[int], int] {
+	var ranges []KeyRange[Builtin[int]]
+	for r, err := range local.Reconcile(remote) {
+		require.NoError(t, err)
+		ranges = append(ranges, r)
+	}
+	for _, r := range ranges {
+		for k := range local.Range(r.Lo, r.Hi, r.Opts) {
+			local = local.Remove(k)
+		}
+		for _, e := range remote.fetch(r) {
+			local = local.Insert(e.K, e.V)
+		}
+	}
+	return local
+}
+
+func TestMerkleReconcile(t *testing.T) {
+	base, _ := randomMerkle(20000, 100000)
+	n := base.Len()
+	for _, edits := range []int{0, 1, 10, 100} {
+		local, remote := base, base
+		for i := range edits {
+			k := Builtin[int]{rand.Intn(100000)}
+			switch i % 3 {
+			case 0:
+				local = local.Insert(k, -1)
+			case 1:
+				remote = remote.Insert(k, -2)
+			default:
+				remote = remote.Remove(remote.Entries()[rand.Intn(remote.Len())].K)
+			}
+		}
+		transport := serve(remote)
+		synced := pull(t, local, transport)
+		close(transport.requests)
+		validateMerkle(t, synced)
+		require.Equal(t, remote.RootHash(), synced.RootHash(), "%v edits", edits)
+		require.Equal(t, remote.Entries(), synced.Entries())
+
+		// O(log N) round trips exchanging O(D log N) fingerprints
+		require.LessOrEqual(t, transport.rounds, bits.Len(uint(n)))
+		require.LessOrEqual(t, transport.sent, 1+2*edits*bits.Len(uint(n)))
+		if edits == 0 {
+			require.Equal(t, 1, transport.rounds)
+		}
+	}
+
+	// reconciling with an empty map reports everything at once
+	transport := serve(NewMerkle(merkleHasher))
+	synced := pull(t, base, transport)
+	require.Equal(t, 0, synced.Len())
+	require.Equal(t, 1, transport.rounds)
+	synced = pull(t, NewMerkle(merkleHasher), serve(base))
+	require.Equal(t, base.Entries(), synced.Entries())
+
+	// the map itself is a peer
+	for range base.Reconcile(base) {
+		t.Fatal("no differences expected")
+	}
+
+	transport.fail = true
+	for _, err := range base.Reconcile(transport) {
+		require.ErrorContains(t, err, "connection reset")
+	}
+	for _, err := range base.Reconcile(brokenPeer{}) {
+		require.ErrorContains(t, err, "wrong number of fingerprints")
+	}
+}
+
+type brokenPeer struct{}
+
+func (brokenPeer) Fingerprints([]KeyRange[Builtin[int]]) ([]Fingerprint, error) {
+	return nil, nil
+}
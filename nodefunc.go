@@ -0,0 +1,381 @@
+package ordmap
+
+import (
+	"iter"
+	"slices"
+)
+
+// NodeFunc is a persistent ordered map whose keys are ordered by a comparator function
+// instead of a Less method. This allows using third-party types as keys without
+// wrapping them, e.g. time.Time with time.Time.Compare or netip.Addr with netip.Addr.Compare.
+//
+// The comparator must return a negative number when a < b, a positive number when a > b
+// and zero when they are equal, same as cmp.Compare. Every comparison is three-way,
+// so descending the tree costs a single comparator call per level.
+//
+// Maps must be created with NewFunc or one of the FromSeqFunc family, which take the
+// comparator. The zero value has none: it reports a length of zero, but any operation
+// that needs to compare keys panics.
+type NodeFunc[K, V any] struct {
+	cmp  func(a, b K) int
+	root *tree[K, V, struct{}]
+}
+
+// funcOrder orders the keys of a NodeFunc by its comparator.
+type funcOrder[K, V, A any] struct {
+	cmp func(a, b K) int
+}
+
+func (o funcOrder[K, V, A]) compare(a, b K) int {
+	return o.cmp(a, b)
+}
+
+func (o funcOrder[K, V, A]) find(node *tree[K, V, A], key K) *tree[K, V, A] {
+	for node != nil {
+		d := o.cmp(key, node.entry.K)
+		if d < 0 {
+			node = node.children[0]
+		} else if d > 0 {
+			node = node.children[1]
+		} else {
+			return node
+		}
+	}
+	return nil
+}
+
+func (o funcOrder[K, V, A]) search(node *tree[K, V, A], key K) (p path, found bool) {
+	for node != nil {
+		d := o.cmp(key, node.entry.K)
+		if d < 0 {
+			p.push(0)
+			node = node.children[0]
+		} else if d > 0 {
+			p.push(1)
+			node = node.children[1]
+		} else {
+			p.push(0)
+			return p, true
+		}
+	}
+	return p, false
+}
+
+// NewFunc returns an empty NodeFunc (map) ordered by cmp.
+// It panics if cmp is nil.
+func NewFunc[K, V any](cmp func(a, b K) int) NodeFunc[K, V] {
+	if cmp == nil {
+		panic("ordmap: NewFunc called with a nil comparator")
+	}
+	return NodeFunc[K, V]{cmp: cmp}
+}
+
+// FromSortedFunc builds a perfectly balanced map from entries sorted by strictly ascending key.
+// See FromSorted for details.
+func FromSortedFunc[K, V any](cmp func(a, b K) int, entries []Entry[K, V]) NodeFunc[K, V] {
+	m := NewFunc[K, V](cmp)
+	return m.with(m.core().fromSorted(entries))
+}
+
+// FromSortedSeqFunc builds a perfectly balanced map from a sequence sorted by strictly ascending key.
+// See FromSortedSeq for details.
+func FromSortedSeqFunc[K, V any](cmp func(a, b K) int, seq iter.Seq2[K, V]) NodeFunc[K, V] {
+	var entries []Entry[K, V]
+	for k, v := range seq {
+		entries = append(entries, Entry[K, V]{k, v})
+	}
+	return FromSortedFunc(cmp, entries)
+}
+
+// FromSeqFunc builds a map from a sequence in arbitrary order.
+// See FromSeq for details.
+func FromSeqFunc[K, V any](cmp func(a, b K) int, seq iter.Seq2[K, V]) NodeFunc[K, V] {
+	var entries []Entry[K, V]
+	for k, v := range seq {
+		entries = append(entries, Entry[K, V]{k, v})
+	}
+	m := NewFunc[K, V](cmp)
+	return m.with(m.core().fromEntries(entries))
+}
+
+// core returns the algorithms ordered by the comparator of the map.
+func (m NodeFunc[K, V]) core() core[K, V, struct{}, funcOrder[K, V, struct{}]] {
+	if m.cmp == nil {
+		panic("ordmap: NodeFunc has no comparator, create maps with NewFunc")
+	}
+	return core[K, V, struct{}, funcOrder[K, V, struct{}]]{ord: funcOrder[K, V, struct{}]{m.cmp}}
+}
+
+func (m NodeFunc[K, V]) with(root *tree[K, V, struct{}]) NodeFunc[K, V] {
+	return NodeFunc[K, V]{m.cmp, root}
+}
+
+// Get retrieves the value for the given key.
+// It returns the value and true if the key exists, otherwise the zero value and false.
+func (m NodeFunc[K, V]) Get(key K) (value V, ok bool) {
+	return m.core().get(m.root, key)
+}
+
+// Insert adds a key-value pair to the map.
+// If the key already exists, its value is updated.
+// Returns a new map containing the change.
+func (m NodeFunc[K, V]) Insert(key K, value V) NodeFunc[K, V] {
+	return m.with(m.core().insert(m.root, key, value, nil))
+}
+
+// InsertFunc adds a key-value pair to the map like Insert, but if the key already
+// exists and eq reports the old and new values as equal, the map is returned unchanged.
+func (m NodeFunc[K, V]) InsertFunc(key K, value V, eq func(old, new V) bool) NodeFunc[K, V] {
+	return m.with(m.core().insert(m.root, key, value, eq))
+}
+
+// Update inserts, modifies or deletes the entry for key in a single descent.
+// See Node.Update for details.
+func (m NodeFunc[K, V]) Update(key K, fn func(old V, exists bool) (V, bool)) NodeFunc[K, V] {
+	return m.with(m.core().update(m.root, key, fn))
+}
+
+// Remove deletes the key from the map.
+// If the key does not exist, the map is returned unchanged.
+// Returns a new map containing the change.
+func (m NodeFunc[K, V]) Remove(key K) NodeFunc[K, V] {
+	return m.with(m.core().remove(m.root, key))
+}
+
+// Split partitions the map around key.
+// See Node.Split for details.
+func (m NodeFunc[K, V]) Split(key K) (left NodeFunc[K, V], value V, found bool, right NodeFunc[K, V]) {
+	l, value, found, r := m.core().split(m.root, key)
+	return m.with(l), value, found, m.with(r)
+}
+
+// Join concatenates two maps where all keys in m are less than all keys in right.
+// See Join for details.
+func (m NodeFunc[K, V]) Join(right NodeFunc[K, V]) NodeFunc[K, V] {
+	return m.with(m.core().concat(m.root, right.root))
+}
+
+// Union returns a map containing all keys from m and b, which must share the same ordering.
+// See Union for details.
+func (m NodeFunc[K, V]) Union(b NodeFunc[K, V], resolve func(k K, va, vb V) V) NodeFunc[K, V] {
+	return m.with(m.core().union(m.root, b.root, resolve))
+}
+
+// Intersection returns a map containing only keys present in both m and b,
+// which must share the same ordering.
+// See Intersection for details.
+func (m NodeFunc[K, V]) Intersection(b NodeFunc[K, V], resolve func(k K, va, vb V) V) NodeFunc[K, V] {
+	return m.with(m.core().intersection(m.root, b.root, resolve))
+}
+
+// Difference returns a map containing the entries of m whose keys are not present in b,
+// which must share the same ordering.
+// See Difference for details.
+func (m NodeFunc[K, V]) Difference(b NodeFunc[K, V]) NodeFunc[K, V] {
+	return m.with(m.core().difference(m.root, b.root))
+}
+
+// RemoveRange deletes all keys between lo and hi.
+// See Node.RemoveRange for details.
+func (m NodeFunc[K, V]) RemoveRange(lo, hi K, opts RangeOptions) NodeFunc[K, V] {
+	return m.with(m.core().removeRange(m.root, lo, hi, opts))
+}
+
+// TruncateBefore deletes all keys strictly less than key.
+func (m NodeFunc[K, V]) TruncateBefore(key K) NodeFunc[K, V] {
+	return m.with(m.core().keepAbove(m.root, key, true))
+}
+
+// TruncateAfter deletes all keys strictly greater than key.
+func (m NodeFunc[K, V]) TruncateAfter(key K) NodeFunc[K, V] {
+	return m.with(m.core().keepBelow(m.root, key, true))
+}
+
+// Len returns the number of elements in the map.
+func (m NodeFunc[K, V]) Len() int {
+	return m.root.length()
+}
+
+// Entries returns a slice of all key-value pairs in the map, sorted by key.
+func (m NodeFunc[K, V]) Entries() []Entry[K, V] {
+	return m.root.entries()
+}
+
+// Min returns the entry with the smallest key in the map.
+// Returns nil if the map is empty.
+func (m NodeFunc[K, V]) Min() *Entry[K, V] {
+	return m.root.extreme(0)
+}
+
+// Max returns the entry with the largest key in the map.
+// Returns nil if the map is empty.
+func (m NodeFunc[K, V]) Max() *Entry[K, V] {
+	return m.root.extreme(1)
+}
+
+// PopMin removes the entry with the smallest key in a single descent.
+// Returns the removed entry and the new map, or nil and the unchanged map if it is empty.
+func (m NodeFunc[K, V]) PopMin() (*Entry[K, V], NodeFunc[K, V]) {
+	min, rest := m.core().popExtreme(m.root, 0)
+	return min, m.with(rest)
+}
+
+// PopMax removes the entry with the largest key in a single descent.
+// Returns the removed entry and the new map, or nil and the unchanged map if it is empty.
+func (m NodeFunc[K, V]) PopMax() (*Entry[K, V], NodeFunc[K, V]) {
+	max, rest := m.core().popExtreme(m.root, 1)
+	return max, m.with(rest)
+}
+
+// DeleteMin removes the entry with the smallest key.
+// Returns a new map containing the change.
+func (m NodeFunc[K, V]) DeleteMin() NodeFunc[K, V] {
+	_, rest := m.PopMin()
+	return rest
+}
+
+// DeleteMax removes the entry with the largest key.
+// Returns a new map containing the change.
+func (m NodeFunc[K, V]) DeleteMax() NodeFunc[K, V] {
+	_, rest := m.PopMax()
+	return rest
+}
+
+// Floor returns the entry with the greatest key less than or equal to key.
+// Returns nil if there is no such entry.
+func (m NodeFunc[K, V]) Floor(key K) *Entry[K, V] {
+	return m.core().neighbor(m.root, key, 0, true)
+}
+
+// Ceiling returns the entry with the least key greater than or equal to key.
+// Returns nil if there is no such entry.
+func (m NodeFunc[K, V]) Ceiling(key K) *Entry[K, V] {
+	return m.core().neighbor(m.root, key, 1, true)
+}
+
+// Lower returns the entry with the greatest key strictly less than key.
+// Returns nil if there is no such entry.
+func (m NodeFunc[K, V]) Lower(key K) *Entry[K, V] {
+	return m.core().neighbor(m.root, key, 0, false)
+}
+
+// Higher returns the entry with the least key strictly greater than key.
+// Returns nil if there is no such entry.
+func (m NodeFunc[K, V]) Higher(key K) *Entry[K, V] {
+	return m.core().neighbor(m.root, key, 1, false)
+}
+
+// At returns the entry at position i in key order (0-based).
+// Returns nil if i is out of range.
+func (m NodeFunc[K, V]) At(i int) *Entry[K, V] {
+	return m.root.at(i)
+}
+
+// IndexOf returns the position of key in key order (0-based) and true if the key exists,
+// otherwise 0 and false.
+func (m NodeFunc[K, V]) IndexOf(key K) (int, bool) {
+	return m.core().indexOf(m.root, key)
+}
+
+// Rank returns the number of keys in the map that are strictly less than key.
+func (m NodeFunc[K, V]) Rank(key K) int {
+	return m.core().rank(m.root, key)
+}
+
+// All returns an iterator over all key-value pairs in the map, sorted by key (ascending).
+func (m NodeFunc[K, V]) All() iter.Seq2[K, V] {
+	return m.root.all(1)
+}
+
+// Backward returns an iterator over all key-value pairs in the map, sorted by key (descending).
+func (m NodeFunc[K, V]) Backward() iter.Seq2[K, V] {
+	return m.root.all(0)
+}
+
+// From returns an iterator over key-value pairs starting from the first key >= k.
+// The iteration proceeds in ascending order.
+func (m NodeFunc[K, V]) From(k K) iter.Seq2[K, V] {
+	return m.core().from(m.root, k, 1)
+}
+
+// BackwardFrom returns an iterator over key-value pairs starting from the first key <= k.
+// The iteration proceeds in descending order.
+func (m NodeFunc[K, V]) BackwardFrom(k K) iter.Seq2[K, V] {
+	return m.core().from(m.root, k, 0)
+}
+
+// Range returns an iterator over key-value pairs with keys between lo and hi.
+// opts controls whether each end is inclusive, exclusive or unbounded.
+// The iteration proceeds in ascending order.
+func (m NodeFunc[K, V]) Range(lo, hi K, opts RangeOptions) iter.Seq2[K, V] {
+	return m.core().within(m.root, lo, hi, opts, 1)
+}
+
+// BackwardRange returns an iterator over key-value pairs with keys between lo and hi.
+// opts controls whether each end is inclusive, exclusive or unbounded.
+// The iteration proceeds in descending order.
+func (m NodeFunc[K, V]) BackwardRange(lo, hi K, opts RangeOptions) iter.Seq2[K, V] {
+	return m.core().within(m.root, lo, hi, opts, 0)
+}
+
+// Keys returns an iterator over all keys in the map in ascending order.
+func (m NodeFunc[K, V]) Keys() iter.Seq[K] {
+	return m.root.keys()
+}
+
+// Values returns an iterator over all values in the map in ascending order of their keys.
+func (m NodeFunc[K, V]) Values() iter.Seq[V] {
+	return m.root.values()
+}
+
+// KeysSlice returns a slice of all keys in the map in ascending order.
+func (m NodeFunc[K, V]) KeysSlice() []K {
+	return slices.AppendSeq(make([]K, 0, m.Len()), m.Keys())
+}
+
+// ValuesSlice returns a slice of all values in the map in ascending order of their keys.
+func (m NodeFunc[K, V]) ValuesSlice() []V {
+	return slices.AppendSeq(make([]V, 0, m.Len()), m.Values())
+}
+
+// KeysRange returns an iterator over the keys between lo and hi in ascending order.
+// See Range for details.
+func (m NodeFunc[K, V]) KeysRange(lo, hi K, opts RangeOptions) iter.Seq[K] {
+	return keysOf(m.Range(lo, hi, opts))
+}
+
+// ValuesRange returns an iterator over the values of keys between lo and hi
+// in ascending order of their keys.
+// See Range for details.
+func (m NodeFunc[K, V]) ValuesRange(lo, hi K, opts RangeOptions) iter.Seq[V] {
+	return valuesOf(m.Range(lo, hi, opts))
+}
+
+// CursorFunc is a bidirectional iterator over a NodeFunc.
+// See Cursor for details.
+type CursorFunc[K, V any] struct {
+	cursor[K, V, struct{}, funcOrder[K, V, struct{}]]
+}
+
+// Cursor returns a new unpositioned cursor over the map.
+func (m NodeFunc[K, V]) Cursor() *CursorFunc[K, V] {
+	return &CursorFunc[K, V]{newCursor(m.core(), m.root)}
+}
+
+// BuilderFunc is a mutable (transient) view of a NodeFunc used for efficient batch construction.
+// See Builder for details.
+type BuilderFunc[K, V any] struct {
+	builder[K, V, struct{}, funcOrder[K, V, struct{}]]
+}
+
+// Builder returns a BuilderFunc starting out with the contents of the map.
+// The map itself is never modified.
+func (m NodeFunc[K, V]) Builder() *BuilderFunc[K, V] {
+	return &BuilderFunc[K, V]{newBuilder(m.core(), m.root)}
+}
+
+// Freeze returns the current contents of the builder as a persistent map.
+// See Builder.Freeze for details.
+func (b *BuilderFunc[K, V]) Freeze() NodeFunc[K, V] {
+	return NodeFunc[K, V]{b.c.ord.cmp, b.freeze()}
+}
@@ -0,0 +1,107 @@
+package ordmap
+
+import (
+	"cmp"
+	"iter"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func validateFunc[K, V any](t *testing.T, m NodeFunc[K, V]) {
+	validateTree(t, m.core(), m.root)
+}
+
+func TestNodeFunc(t *testing.T) {
+	testVariant(t, variant[NodeFunc[int, int]]{
+		empty: func() NodeFunc[int, int] { return NewFunc[int, int](cmp.Compare[int]) },
+		fromSorted: func(entries []Entry[int, int]) NodeFunc[int, int] {
+			return FromSortedFunc(cmp.Compare[int], entries)
+		},
+		fromSeq: func(seq iter.Seq2[int, int]) NodeFunc[int, int] {
+			return FromSeqFunc(cmp.Compare[int], seq)
+		},
+		join:         NodeFunc[int, int].Join,
+		union:        NodeFunc[int, int].Union,
+		intersection: NodeFunc[int, int].Intersection,
+		difference:   NodeFunc[int, int].Difference,
+		cursor:       func(m NodeFunc[int, int]) intCursor { return m.Cursor() },
+		builder:      func(m NodeFunc[int, int]) intBuilder[NodeFunc[int, int]] { return m.Builder() },
+		validate:     validateFunc[int, int],
+		root:         func(m NodeFunc[int, int]) any { return m.root },
+	})
+}
+
+func TestNodeFuncConstructors(t *testing.T) {
+	m := FromSeqFunc(cmp.Compare[int], func(yield func(int, int) bool) {
+		for i := range 10 {
+			if !yield(9-i, i) {
+				return
+			}
+		}
+	})
+	sorted := FromSortedSeqFunc(cmp.Compare[int], m.All())
+	validateFunc(t, sorted)
+	require.Equal(t, m.Entries(), sorted.Entries())
+}
+
+func TestNodeFuncZeroValue(t *testing.T) {
+	var m NodeFunc[int, int]
+	require.Equal(t, 0, m.Len())
+	require.Empty(t, m.Entries())
+	require.PanicsWithValue(t, "ordmap: NodeFunc has no comparator, create maps with NewFunc", func() {
+		m.Insert(1, 1)
+	})
+	require.Panics(t, func() { NewFunc[int, int](nil) })
+}
+
+func TestNodeFuncCustomOrder(t *testing.T) {
+	// descending order, without any wrapper type
+	desc := NewFunc[int, string](func(a, b int) int { return cmp.Compare(b, a) })
+	for i := range 10 {
+		desc = desc.Insert(i, "")
+	}
+	validateFunc(t, desc)
+	require.Equal(t, 9, desc.Min().K)
+	require.Equal(t, 0, desc.Max().K)
+	require.Equal(t, 5, desc.Ceiling(5).K)
+	require.Equal(t, 4, desc.Higher(5).K)
+
+	// third-party key types with a Compare method
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := NewFunc[time.Time, int](time.Time.Compare)
+	for i := range 24 {
+		events = events.Insert(start.Add(time.Duration(i)*time.Hour), i)
+	}
+	// same instant in a different location is the same key
+	value, ok := events.Get(start.Add(3 * time.Hour).In(time.FixedZone("X", 3600)))
+	require.True(t, ok)
+	require.Equal(t, 3, value)
+	require.Equal(t, 10, events.Rank(start.Add(10*time.Hour)))
+}
+
+func BenchmarkNodeFunc(b *testing.B) {
+	N := 1000
+	keys := rand.Perm(N)
+	b.Run("insert", func(b *testing.B) {
+		for range b.N {
+			m := NewFunc[int, int](cmp.Compare[int])
+			for _, k := range keys {
+				m = m.Insert(k, k)
+			}
+		}
+	})
+	m := NewFunc[int, int](cmp.Compare[int])
+	for _, k := range keys {
+		m = m.Insert(k, k)
+	}
+	b.Run("get", func(b *testing.B) {
+		for range b.N {
+			for _, k := range keys {
+				m.Get(k)
+			}
+		}
+	})
+}
@@ -0,0 +1,458 @@
+package ordmap
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+)
+
+// NewOrdered returns an empty NodeOrdered (map).
+func NewOrdered[K cmp.Ordered, V any]() *NodeOrdered[K, V] {
+	return nil
+}
+
+// FromSortedOrdered builds a perfectly balanced map from entries sorted by strictly ascending key.
+// The ordering precondition is not checked.
+// Runs in O(N), as opposed to O(N log N) for repeated Insert.
+func FromSortedOrdered[K cmp.Ordered, V any](entries []Entry[K, V]) *NodeOrdered[K, V] {
+	return asOrdered(orderedCore[K, V]().fromSorted(entries))
+}
+
+// FromSortedSeqOrdered builds a perfectly balanced map from a sequence sorted by strictly ascending key.
+// The ordering precondition is not checked.
+// Runs in O(N).
+func FromSortedSeqOrdered[K cmp.Ordered, V any](seq iter.Seq2[K, V]) *NodeOrdered[K, V] {
+	var entries []Entry[K, V]
+	for k, v := range seq {
+		entries = append(entries, Entry[K, V]{k, v})
+	}
+	return FromSortedOrdered(entries)
+}
+
+// FromSeqOrdered builds a map from a sequence in arbitrary order.
+// See FromSeq for details.
+func FromSeqOrdered[K cmp.Ordered, V any](seq iter.Seq2[K, V]) *NodeOrdered[K, V] {
+	var entries []Entry[K, V]
+	for k, v := range seq {
+		entries = append(entries, Entry[K, V]{k, v})
+	}
+	return asOrdered(orderedCore[K, V]().fromEntries(entries))
+}
+
+// NodeOrdered is a map for keys of any type in cmp.Ordered. It works exactly like Node,
+// but compares keys with < instead of calling a Less method, and unlike
+// NodeBuiltin it does not wrap keys, so iteration and Entries need no conversion.
+//
+// Floating point NaN keys are ordered like cmp.Compare does: all NaNs are treated
+// as the same key, which sorts before any other value.
+// A nil *NodeOrdered represents an empty map.
+type NodeOrdered[K cmp.Ordered, V any] tree[K, V, struct{}]
+
+func (node *NodeOrdered[K, V]) tree() *tree[K, V, struct{}] {
+	return (*tree[K, V, struct{}])(node)
+}
+
+func asOrdered[K cmp.Ordered, V any](t *tree[K, V, struct{}]) *NodeOrdered[K, V] {
+	return (*NodeOrdered[K, V])(t)
+}
+
+// cmpOrder orders the keys of a NodeOrdered like cmp.Compare, but compares them with <.
+type cmpOrder[K cmp.Ordered, V, A any] struct{}
+
+func (cmpOrder[K, V, A]) compare(a, b K) int {
+	return cmp.Compare(a, b)
+}
+
+// less reports whether a sorts before b, same as cmp.Less: with < except that NaN,
+// for which < is always false, sorts before any other value. For key types that
+// cannot hold NaN the check is compiled away.
+func (cmpOrder[K, V, A]) less(a, b K) bool {
+	return a < b || isNaN(a) && !isNaN(b)
+}
+
+// isNaN reports whether x is a floating point NaN, the only value not equal to itself.
+func isNaN[K cmp.Ordered](x K) bool {
+	return x != x
+}
+
+func (o cmpOrder[K, V, A]) find(node *tree[K, V, A], key K) *tree[K, V, A] {
+	for node != nil {
+		if o.less(key, node.entry.K) {
+			node = node.children[0]
+		} else if o.less(node.entry.K, key) {
+			node = node.children[1]
+		} else {
+			return node
+		}
+	}
+	return nil
+}
+
+// search compares only once per level, like lessOrder.search.
+func (o cmpOrder[K, V, A]) search(node *tree[K, V, A], key K) (p path, found bool) {
+	var candidate *tree[K, V, A]
+	at := 0
+	for node != nil {
+		if o.less(key, node.entry.K) {
+			p.push(0)
+			node = node.children[0]
+		} else {
+			candidate, at = node, p.len
+			p.push(1)
+			node = node.children[1]
+		}
+	}
+	if found = candidate != nil && !o.less(candidate.entry.K, key); found {
+		p.cut(at)
+	}
+	return p, found
+}
+
+// insert is core.insert recursing with less rather than searching through the ordering.
+func (o cmpOrder[K, V, A]) insert(c core[K, V, A, cmpOrder[K, V, A]], node *tree[K, V, A], key K, value V, eq func(old, new V) bool) *tree[K, V, A] {
+	if node == nil {
+		return c.mk(Entry[K, V]{key, value}, nil, nil)
+	}
+	entry, left, right := node.entry, node.children[0], node.children[1]
+	if o.less(node.entry.K, key) {
+		right = o.insert(c, right, key, value, eq)
+		if right == node.children[1] {
+			return node
+		}
+	} else if o.less(key, node.entry.K) {
+		left = o.insert(c, left, key, value, eq)
+		if left == node.children[0] {
+			return node
+		}
+	} else { // equals
+		if eq != nil && eq(entry.V, value) {
+			return node
+		}
+		entry = Entry[K, V]{key, value}
+	}
+	return c.rotate(entry, left, right)
+}
+
+// remove is core.remove recursing with less rather than searching through the ordering.
+func (o cmpOrder[K, V, A]) remove(c core[K, V, A, cmpOrder[K, V, A]], node *tree[K, V, A], key K) *tree[K, V, A] {
+	if node == nil {
+		return nil
+	}
+	entry, left, right := node.entry, node.children[0], node.children[1]
+	if o.less(node.entry.K, key) {
+		right = o.remove(c, right, key)
+		if right == node.children[1] {
+			return node
+		}
+	} else if o.less(key, node.entry.K) {
+		left = o.remove(c, left, key)
+		if left == node.children[0] {
+			return node
+		}
+	} else { // equals
+		return c.without(node)
+	}
+	return c.rotate(entry, left, right)
+}
+
+func orderedCore[K cmp.Ordered, V any]() core[K, V, struct{}, cmpOrder[K, V, struct{}]] {
+	return core[K, V, struct{}, cmpOrder[K, V, struct{}]]{}
+}
+
+// Get retrieves the value for the given key.
+// It returns the value and true if the key exists, otherwise the zero value and false.
+func (node *NodeOrdered[K, V]) Get(key K) (value V, ok bool) {
+	// the loop of cmpOrder.find, written out so that it is not behind a call
+	var o cmpOrder[K, V, struct{}]
+	for finger := node.tree(); finger != nil; {
+		if o.less(key, finger.entry.K) {
+			finger = finger.children[0]
+		} else if o.less(finger.entry.K, key) {
+			finger = finger.children[1]
+		} else {
+			return finger.entry.V, true
+		}
+	}
+	return // using named returns so we keep the zero value for `value`
+}
+
+// Insert adds a key-value pair to the map.
+// If the key already exists, its value is updated.
+// Returns a new map containing the change.
+func (node *NodeOrdered[K, V]) Insert(key K, value V) *NodeOrdered[K, V] {
+	return asOrdered(cmpOrder[K, V, struct{}]{}.insert(orderedCore[K, V](), node.tree(), key, value, nil))
+}
+
+// InsertFunc adds a key-value pair to the map like Insert, but if the key already
+// exists and eq reports the old and new values as equal, the existing entry is kept
+// and the map is returned unchanged (the very same pointer).
+// This allows cheap change detection via pointer equality.
+func (node *NodeOrdered[K, V]) InsertFunc(key K, value V, eq func(old, new V) bool) *NodeOrdered[K, V] {
+	return asOrdered(cmpOrder[K, V, struct{}]{}.insert(orderedCore[K, V](), node.tree(), key, value, eq))
+}
+
+// Update inserts, modifies or deletes the entry for key in a single descent.
+// fn receives the current value (or the zero value) and whether the key exists.
+// It returns the new value and whether the entry should be kept in the map.
+// If the key does not exist and fn does not keep it, the map is returned unchanged.
+// Returns a new map containing the change.
+func (node *NodeOrdered[K, V]) Update(key K, fn func(old V, exists bool) (V, bool)) *NodeOrdered[K, V] {
+	return asOrdered(orderedCore[K, V]().update(node.tree(), key, fn))
+}
+
+// Remove deletes the key from the map.
+// If the key does not exist, the map is returned unchanged (the very same pointer).
+// Returns a new map containing the change.
+func (node *NodeOrdered[K, V]) Remove(key K) *NodeOrdered[K, V] {
+	return asOrdered(cmpOrder[K, V, struct{}]{}.remove(orderedCore[K, V](), node.tree(), key))
+}
+
+// Split partitions the map around key.
+// left contains all keys less than key and right all keys greater than key.
+// If key is present, its value is returned and found is true.
+// Runs in O(log N) and shares structure with the original map.
+func (node *NodeOrdered[K, V]) Split(key K) (left *NodeOrdered[K, V], value V, found bool, right *NodeOrdered[K, V]) {
+	l, value, found, r := orderedCore[K, V]().split(node.tree(), key)
+	return asOrdered(l), value, found, asOrdered(r)
+}
+
+// JoinOrdered concatenates two maps where all keys in left are less than all keys in right.
+// The ordering precondition is not checked.
+// Runs in O(log N) and shares structure with both inputs.
+func JoinOrdered[K cmp.Ordered, V any](left, right *NodeOrdered[K, V]) *NodeOrdered[K, V] {
+	return asOrdered(orderedCore[K, V]().concat(left.tree(), right.tree()))
+}
+
+// UnionOrdered returns a map containing all keys from a and b.
+// For keys present in both maps the value is resolve(k, va, vb); if resolve is nil,
+// the value from b wins.
+// Runs in O(m log(n/m + 1)) for sizes m <= n and shares unchanged subtrees with the inputs.
+func UnionOrdered[K cmp.Ordered, V any](a, b *NodeOrdered[K, V], resolve func(k K, va, vb V) V) *NodeOrdered[K, V] {
+	return asOrdered(orderedCore[K, V]().union(a.tree(), b.tree(), resolve))
+}
+
+// IntersectionOrdered returns a map containing only keys present in both a and b.
+// The value for each key is resolve(k, va, vb); if resolve is nil, the value from b wins.
+// Runs in O(m log(n/m + 1)) for sizes m <= n.
+func IntersectionOrdered[K cmp.Ordered, V any](a, b *NodeOrdered[K, V], resolve func(k K, va, vb V) V) *NodeOrdered[K, V] {
+	return asOrdered(orderedCore[K, V]().intersection(a.tree(), b.tree(), resolve))
+}
+
+// DifferenceOrdered returns a map containing the entries of a whose keys are not present in b.
+// Runs in O(m log(n/m + 1)) for sizes m <= n and shares unchanged subtrees with a.
+func DifferenceOrdered[K cmp.Ordered, V any](a, b *NodeOrdered[K, V]) *NodeOrdered[K, V] {
+	return asOrdered(orderedCore[K, V]().difference(a.tree(), b.tree()))
+}
+
+// RemoveRange deletes all keys between lo and hi.
+// opts controls whether each end is inclusive, exclusive or unbounded.
+// Whole subtrees within the range are dropped at once, so this runs in O(log N)
+// regardless of how many entries are removed.
+// If no keys fall within the range, the map is returned unchanged.
+func (node *NodeOrdered[K, V]) RemoveRange(lo, hi K, opts RangeOptions) *NodeOrdered[K, V] {
+	return asOrdered(orderedCore[K, V]().removeRange(node.tree(), lo, hi, opts))
+}
+
+// TruncateBefore deletes all keys strictly less than key.
+// Runs in O(log N) regardless of how many entries are removed.
+func (node *NodeOrdered[K, V]) TruncateBefore(key K) *NodeOrdered[K, V] {
+	return asOrdered(orderedCore[K, V]().keepAbove(node.tree(), key, true))
+}
+
+// TruncateAfter deletes all keys strictly greater than key.
+// Runs in O(log N) regardless of how many entries are removed.
+func (node *NodeOrdered[K, V]) TruncateAfter(key K) *NodeOrdered[K, V] {
+	return asOrdered(orderedCore[K, V]().keepBelow(node.tree(), key, true))
+}
+
+// Len returns the number of elements in the map.
+func (node *NodeOrdered[K, V]) Len() int {
+	return node.tree().length()
+}
+
+// Entries returns a slice of all key-value pairs in the map, sorted by key.
+func (node *NodeOrdered[K, V]) Entries() []Entry[K, V] {
+	return node.tree().entries()
+}
+
+// Min returns the entry with the smallest key in the map.
+// Returns nil if the map is empty.
+func (node *NodeOrdered[K, V]) Min() *Entry[K, V] {
+	return node.tree().extreme(0)
+}
+
+// Max returns the entry with the largest key in the map.
+// Returns nil if the map is empty.
+func (node *NodeOrdered[K, V]) Max() *Entry[K, V] {
+	return node.tree().extreme(1)
+}
+
+// Floor returns the entry with the greatest key less than or equal to key.
+// Returns nil if there is no such entry.
+func (node *NodeOrdered[K, V]) Floor(key K) *Entry[K, V] {
+	return orderedCore[K, V]().neighbor(node.tree(), key, 0, true)
+}
+
+// Ceiling returns the entry with the least key greater than or equal to key.
+// Returns nil if there is no such entry.
+func (node *NodeOrdered[K, V]) Ceiling(key K) *Entry[K, V] {
+	return orderedCore[K, V]().neighbor(node.tree(), key, 1, true)
+}
+
+// Lower returns the entry with the greatest key strictly less than key.
+// Returns nil if there is no such entry.
+func (node *NodeOrdered[K, V]) Lower(key K) *Entry[K, V] {
+	return orderedCore[K, V]().neighbor(node.tree(), key, 0, false)
+}
+
+// Higher returns the entry with the least key strictly greater than key.
+// Returns nil if there is no such entry.
+func (node *NodeOrdered[K, V]) Higher(key K) *Entry[K, V] {
+	return orderedCore[K, V]().neighbor(node.tree(), key, 1, false)
+}
+
+// PopMin removes the entry with the smallest key in a single descent.
+// Returns the removed entry and the new map, or nil and the unchanged map if it is empty.
+func (node *NodeOrdered[K, V]) PopMin() (*Entry[K, V], *NodeOrdered[K, V]) {
+	min, rest := orderedCore[K, V]().popExtreme(node.tree(), 0)
+	return min, asOrdered(rest)
+}
+
+// PopMax removes the entry with the largest key in a single descent.
+// Returns the removed entry and the new map, or nil and the unchanged map if it is empty.
+func (node *NodeOrdered[K, V]) PopMax() (*Entry[K, V], *NodeOrdered[K, V]) {
+	max, rest := orderedCore[K, V]().popExtreme(node.tree(), 1)
+	return max, asOrdered(rest)
+}
+
+// DeleteMin removes the entry with the smallest key.
+// Returns a new map containing the change.
+func (node *NodeOrdered[K, V]) DeleteMin() *NodeOrdered[K, V] {
+	_, rest := node.PopMin()
+	return rest
+}
+
+// DeleteMax removes the entry with the largest key.
+// Returns a new map containing the change.
+func (node *NodeOrdered[K, V]) DeleteMax() *NodeOrdered[K, V] {
+	_, rest := node.PopMax()
+	return rest
+}
+
+// At returns the entry at position i in key order (0-based).
+// Returns nil if i is out of range.
+// Runs in O(log N) by using the subtree sizes stored in each node.
+func (node *NodeOrdered[K, V]) At(i int) *Entry[K, V] {
+	return node.tree().at(i)
+}
+
+// IndexOf returns the position of key in key order (0-based) and true if the key exists,
+// otherwise 0 and false.
+func (node *NodeOrdered[K, V]) IndexOf(key K) (int, bool) {
+	return orderedCore[K, V]().indexOf(node.tree(), key)
+}
+
+// Rank returns the number of keys in the map that are strictly less than key.
+// The key itself does not need to be present in the map.
+func (node *NodeOrdered[K, V]) Rank(key K) int {
+	return orderedCore[K, V]().rank(node.tree(), key)
+}
+
+// All returns an iterator over all key-value pairs in the map, sorted by key (ascending).
+func (node *NodeOrdered[K, V]) All() iter.Seq2[K, V] {
+	return node.tree().all(1)
+}
+
+// Backward returns an iterator over all key-value pairs in the map, sorted by key (descending).
+func (node *NodeOrdered[K, V]) Backward() iter.Seq2[K, V] {
+	return node.tree().all(0)
+}
+
+// From returns an iterator over key-value pairs starting from the first key >= k.
+// The iteration proceeds in ascending order.
+func (node *NodeOrdered[K, V]) From(k K) iter.Seq2[K, V] {
+	return orderedCore[K, V]().from(node.tree(), k, 1)
+}
+
+// BackwardFrom returns an iterator over key-value pairs starting from the first key <= k.
+// The iteration proceeds in descending order.
+func (node *NodeOrdered[K, V]) BackwardFrom(k K) iter.Seq2[K, V] {
+	return orderedCore[K, V]().from(node.tree(), k, 0)
+}
+
+// Range returns an iterator over key-value pairs with keys between lo and hi.
+// opts controls whether each end is inclusive, exclusive or unbounded.
+// The iteration proceeds in ascending order.
+func (node *NodeOrdered[K, V]) Range(lo, hi K, opts RangeOptions) iter.Seq2[K, V] {
+	return orderedCore[K, V]().within(node.tree(), lo, hi, opts, 1)
+}
+
+// BackwardRange returns an iterator over key-value pairs with keys between lo and hi.
+// opts controls whether each end is inclusive, exclusive or unbounded.
+// The iteration proceeds in descending order.
+func (node *NodeOrdered[K, V]) BackwardRange(lo, hi K, opts RangeOptions) iter.Seq2[K, V] {
+	return orderedCore[K, V]().within(node.tree(), lo, hi, opts, 0)
+}
+
+// Keys returns an iterator over all keys in the map in ascending order.
+func (node *NodeOrdered[K, V]) Keys() iter.Seq[K] {
+	return node.tree().keys()
+}
+
+// Values returns an iterator over all values in the map in ascending order of their keys.
+func (node *NodeOrdered[K, V]) Values() iter.Seq[V] {
+	return node.tree().values()
+}
+
+// KeysSlice returns a slice of all keys in the map in ascending order.
+func (node *NodeOrdered[K, V]) KeysSlice() []K {
+	return slices.AppendSeq(make([]K, 0, node.Len()), node.Keys())
+}
+
+// ValuesSlice returns a slice of all values in the map in ascending order of their keys.
+func (node *NodeOrdered[K, V]) ValuesSlice() []V {
+	return slices.AppendSeq(make([]V, 0, node.Len()), node.Values())
+}
+
+// KeysRange returns an iterator over the keys between lo and hi in ascending order.
+// See Range for details.
+func (node *NodeOrdered[K, V]) KeysRange(lo, hi K, opts RangeOptions) iter.Seq[K] {
+	return keysOf(node.Range(lo, hi, opts))
+}
+
+// ValuesRange returns an iterator over the values of keys between lo and hi
+// in ascending order of their keys.
+// See Range for details.
+func (node *NodeOrdered[K, V]) ValuesRange(lo, hi K, opts RangeOptions) iter.Seq[V] {
+	return valuesOf(node.Range(lo, hi, opts))
+}
+
+// BuilderOrdered is a mutable (transient) view of a NodeOrdered used for efficient batch construction.
+// See Builder for details.
+type BuilderOrdered[K cmp.Ordered, V any] struct {
+	builder[K, V, struct{}, cmpOrder[K, V, struct{}]]
+}
+
+// Builder returns a BuilderOrdered starting out with the contents of the map.
+// The map itself is never modified.
+func (node *NodeOrdered[K, V]) Builder() *BuilderOrdered[K, V] {
+	return &BuilderOrdered[K, V]{newBuilder(orderedCore[K, V](), node.tree())}
+}
+
+// Freeze returns the current contents of the builder as a persistent map,
+// sharing untouched subtrees with the map the builder was created from.
+// The builder remains usable; subsequent writes will no longer affect the returned map.
+func (b *BuilderOrdered[K, V]) Freeze() *NodeOrdered[K, V] {
+	return asOrdered(b.freeze())
+}
+
+// CursorOrdered is a bidirectional iterator over a NodeOrdered.
+// See Cursor for details.
+type CursorOrdered[K cmp.Ordered, V any] struct {
+	cursor[K, V, struct{}, cmpOrder[K, V, struct{}]]
+}
+
+// Cursor returns a new unpositioned cursor over the map.
+func (node *NodeOrdered[K, V]) Cursor() *CursorOrdered[K, V] {
+	return &CursorOrdered[K, V]{newCursor(orderedCore[K, V](), node.tree())}
+}
@@ -0,0 +1,145 @@
+package ordmap
+
+import (
+	"cmp"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func validateNodeOrdered[K cmp.Ordered, V any](t *testing.T, tree *NodeOrdered[K, V]) {
+	validateTree(t, orderedCore[K, V](), tree.tree())
+}
+
+func TestNodeOrdered(t *testing.T) {
+	testVariant(t, variant[*NodeOrdered[int, int]]{
+		empty:        NewOrdered[int, int],
+		fromSorted:   FromSortedOrdered[int, int],
+		fromSeq:      FromSeqOrdered[int, int],
+		join:         JoinOrdered[int, int],
+		union:        UnionOrdered[int, int],
+		intersection: IntersectionOrdered[int, int],
+		difference:   DifferenceOrdered[int, int],
+		cursor:       func(m *NodeOrdered[int, int]) intCursor { return m.Cursor() },
+		builder:      func(m *NodeOrdered[int, int]) intBuilder[*NodeOrdered[int, int]] { return m.Builder() },
+		validate:     validateNodeOrdered[int, int],
+		root:         func(m *NodeOrdered[int, int]) any { return m },
+	})
+}
+
+func TestNodeOrderedConstructors(t *testing.T) {
+	m := FromSeqOrdered(func(yield func(int, int) bool) {
+		for i := range 10 {
+			if !yield(9-i, i) {
+				return
+			}
+		}
+	})
+	sorted := FromSortedSeqOrdered(m.All())
+	validateNodeOrdered(t, sorted)
+	require.Equal(t, m.Entries(), sorted.Entries())
+}
+
+func TestNodeOrderedNaN(t *testing.T) {
+	nan := math.NaN()
+	m := NewOrdered[float64, string]()
+	m = m.Insert(1, "one")
+	m = m.Insert(nan, "nan")
+	m = m.Insert(math.Inf(-1), "-inf")
+	m = m.Insert(-nan, "other nan") // replaces the first NaN
+	validateNodeOrdered(t, m)
+
+	require.Equal(t, 3, m.Len())
+	value, ok := m.Get(nan)
+	require.True(t, ok)
+	require.Equal(t, "other nan", value)
+	require.True(t, math.IsNaN(m.Min().K)) // NaN sorts first
+	require.Equal(t, math.Inf(-1), m.Higher(nan).K)
+	index, ok := m.IndexOf(nan)
+	require.True(t, ok)
+	require.Equal(t, 0, index)
+	require.Equal(t, 1, m.Rank(math.Inf(-1)))
+	left, _, found, right := m.Split(nan)
+	require.True(t, found)
+	require.Nil(t, left)
+	require.Equal(t, 2, right.Len())
+
+	m = m.Remove(nan)
+	require.Equal(t, 2, m.Len())
+	_, ok = m.Get(nan)
+	require.False(t, ok)
+}
+
+func BenchmarkNodeOrdered(b *testing.B) {
+	N := 1000
+	keys := rand.Perm(N)
+	b.Run("insert", func(b *testing.B) {
+		b.Run("ordered", func(b *testing.B) {
+			for range b.N {
+				m := NewOrdered[int, int]()
+				for _, k := range keys {
+					m = m.Insert(k, k)
+				}
+			}
+		})
+		b.Run("builtin", func(b *testing.B) {
+			for range b.N {
+				m := NewBuiltin[int, int]()
+				for _, k := range keys {
+					m = m.Insert(k, k)
+				}
+			}
+		})
+	})
+
+	ordered := NewOrdered[int, int]()
+	builtin := NewBuiltin[int, int]()
+	for _, k := range keys {
+		ordered = ordered.Insert(k, k)
+		builtin = builtin.Insert(k, k)
+	}
+	b.Run("get", func(b *testing.B) {
+		b.Run("ordered", func(b *testing.B) {
+			for range b.N {
+				for _, k := range keys {
+					ordered.Get(k)
+				}
+			}
+		})
+		b.Run("builtin", func(b *testing.B) {
+			for range b.N {
+				for _, k := range keys {
+					builtin.Get(k)
+				}
+			}
+		})
+	})
+	b.Run("all", func(b *testing.B) {
+		b.Run("ordered", func(b *testing.B) {
+			for range b.N {
+				for range ordered.All() {
+				}
+			}
+		})
+		b.Run("builtin", func(b *testing.B) {
+			for range b.N {
+				for range builtin.All() {
+				}
+			}
+		})
+	})
+	b.Run("entries", func(b *testing.B) {
+		b.Run("ordered", func(b *testing.B) {
+			for range b.N {
+				ordered.Entries()
+			}
+		})
+		b.Run("builtin", func(b *testing.B) {
+			for range b.N {
+				builtin.Entries()
+			}
+		})
+	})
+}
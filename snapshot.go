@@ -0,0 +1,335 @@
+package ordmap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"slices"
+)
+
+// A snapshot is laid out as follows, with all integers but the checksum encoded as uvarints:
+//
+//	magic     "ordm"
+//	version   1 byte
+//	count     number of entries
+//	entries   count times: key length, key, value length, value; in ascending key order
+//	checksum  CRC-32C of everything above, 4 bytes little endian
+const (
+	snapshotMagic   = "ordm"
+	snapshotVersion = 1
+)
+
+// ErrInvalidSnapshot is returned when decoding data that is not a well-formed snapshot,
+// including when its checksum does not match.
+var ErrInvalidSnapshot = errors.New("ordmap: invalid snapshot")
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Encoder writes snapshots of maps to a stream.
+// Entries are written one at a time as the map is iterated, so encoding
+// does not need any memory proportional to the size of the map.
+type Encoder[K Comparable[K], V any] struct {
+	w       checksumWriter
+	keys    Codec[K]
+	values  Codec[V]
+	buf     []byte
+	scratch []byte
+}
+
+// NewEncoder returns an encoder writing to w with the given codecs.
+// Writes are buffered unless w is a *bufio.Writer already.
+func NewEncoder[K Comparable[K], V any](w io.Writer, keys Codec[K], values Codec[V]) *Encoder[K, V] {
+	return &Encoder[K, V]{w: newChecksumWriter(w), keys: keys, values: values}
+}
+
+// Encode writes a complete snapshot of node and flushes it to the underlying writer.
+// Several snapshots can be written to the same stream one after another.
+func (e *Encoder[K, V]) Encode(node *Node[K, V]) error {
+	e.w.crc = 0
+	e.buf = append(e.buf[:0], snapshotMagic...)
+	e.buf = append(e.buf, snapshotVersion)
+	e.buf = binary.AppendUvarint(e.buf, uint64(node.Len()))
+	if err := e.w.write(e.buf); err != nil {
+		return err
+	}
+	for k, v := range node.All() {
+		if err := e.encodeEntry(k, v); err != nil {
+			return err
+		}
+	}
+	return e.w.finish()
+}
+
+func (e *Encoder[K, V]) encodeEntry(k K, v V) error {
+	var err error
+	if e.buf, e.scratch, err = appendRecord(e.buf[:0], e.scratch, e.keys, k); err != nil {
+		return err
+	}
+	if e.buf, e.scratch, err = appendRecord(e.buf, e.scratch, e.values, v); err != nil {
+		return err
+	}
+	return e.w.write(e.buf)
+}
+
+// appendRecord appends the length-prefixed encoding of v to buf, using scratch as temporary space.
+func appendRecord[T any](buf, scratch []byte, codec Codec[T], v T) ([]byte, []byte, error) {
+	scratch, err := codec.Append(scratch[:0], v)
+	if err != nil {
+		return buf, scratch, err
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(scratch)))
+	return append(buf, scratch...), scratch, nil
+}
+
+// Decoder reads snapshots of maps from a stream.
+// The map is built directly as entries are read, without an intermediate copy,
+// and comes out perfectly balanced.
+type Decoder[K Comparable[K], V any] struct {
+	r       checksumReader
+	keys    Codec[K]
+	values  Codec[V]
+	prev    K
+	hasPrev bool
+}
+
+// NewDecoder returns a decoder reading from r with the given codecs.
+// Reads are buffered unless r is a *bufio.Reader already, so the decoder may read
+// past the end of a snapshot.
+func NewDecoder[K Comparable[K], V any](r io.Reader, keys Codec[K], values Codec[V]) *Decoder[K, V] {
+	return &Decoder[K, V]{r: newChecksumReader(r), keys: keys, values: values}
+}
+
+// Decode reads the next snapshot from the stream.
+// It returns io.EOF if the stream ends cleanly before a snapshot and
+// an error wrapping ErrInvalidSnapshot if the data is corrupt.
+func (d *Decoder[K, V]) Decode() (*Node[K, V], error) {
+	d.r.crc = 0
+	header, err := d.r.read(len(snapshotMagic) + 1)
+	if err != nil {
+		return nil, err
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, fmt.Errorf("%w: bad magic", ErrInvalidSnapshot)
+	}
+	if version := header[len(snapshotMagic)]; version != snapshotVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidSnapshot, version)
+	}
+	count, err := d.r.readUvarint()
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	var zero K
+	d.prev, d.hasPrev = zero, false
+	node, err := d.build(count)
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	if err := d.r.verify(); err != nil {
+		return nil, noEOF(err)
+	}
+	return node, nil
+}
+
+// build reads the next n entries into a balanced tree, shaped the same as FromSorted would.
+func (d *Decoder[K, V]) build(n uint64) (*Node[K, V], error) {
+	if n == 0 {
+		return nil, nil
+	}
+	mid := n / 2
+	left, err := d.build(mid)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := d.decodeEntry()
+	if err != nil {
+		return nil, err
+	}
+	right, err := d.build(n - mid - 1)
+	if err != nil {
+		return nil, err
+	}
+	return mk_OrdMap(entry, left, right), nil
+}
+
+func (d *Decoder[K, V]) decodeEntry() (entry Entry[K, V], err error) {
+	if entry.K, err = readRecord(&d.r, d.keys); err != nil {
+		return entry, err
+	}
+	if d.hasPrev && !d.prev.Less(entry.K) {
+		return entry, fmt.Errorf("%w: keys out of order", ErrInvalidSnapshot)
+	}
+	d.prev, d.hasPrev = entry.K, true
+	entry.V, err = readRecord(&d.r, d.values)
+	return entry, err
+}
+
+func readRecord[T any](r *checksumReader, codec Codec[T]) (T, error) {
+	var v T
+	n, err := r.readUvarint()
+	if err != nil {
+		return v, err
+	}
+	data, err := r.readLarge(n)
+	if err != nil {
+		return v, err
+	}
+	return codec.Decode(data)
+}
+
+// MarshalSnapshot encodes node as a snapshot, see Encoder.
+func MarshalSnapshot[K Comparable[K], V any](node *Node[K, V], keys Codec[K], values Codec[V]) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf, keys, values).Encode(node); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalSnapshot decodes a snapshot produced by MarshalSnapshot, see Decoder.
+// Data following the snapshot is an error.
+func UnmarshalSnapshot[K Comparable[K], V any](data []byte, keys Codec[K], values Codec[V]) (*Node[K, V], error) {
+	r := bytes.NewReader(data)
+	d := NewDecoder(r, keys, values)
+	node, err := d.Decode()
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	if r.Len() > 0 || d.r.r.Buffered() > 0 {
+		return nil, fmt.Errorf("%w: trailing data", ErrInvalidSnapshot)
+	}
+	return node, nil
+}
+
+// MarshalSnapshotBuiltin encodes the map as a snapshot.
+// See MarshalSnapshot for details.
+func MarshalSnapshotBuiltin[K BuiltinComparable, V any](n NodeBuiltin[K, V], keys Codec[K], values Codec[V]) ([]byte, error) {
+	return MarshalSnapshot(n.n, BuiltinCodec[K]{keys}, values)
+}
+
+// UnmarshalSnapshotBuiltin decodes a snapshot produced by MarshalSnapshotBuiltin.
+// See UnmarshalSnapshot for details.
+func UnmarshalSnapshotBuiltin[K BuiltinComparable, V any](data []byte, keys Codec[K], values Codec[V]) (NodeBuiltin[K, V], error) {
+	node, err := UnmarshalSnapshot(data, BuiltinCodec[K]{keys}, values)
+	return NodeBuiltin[K, V]{node}, err
+}
+
+// noEOF turns an io.EOF in the middle of a snapshot into io.ErrUnexpectedEOF.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// checksumWriter buffers writes and keeps a running checksum of them.
+type checksumWriter struct {
+	w   *bufio.Writer
+	crc uint32
+}
+
+func newChecksumWriter(w io.Writer) checksumWriter {
+	bw, ok := w.(*bufio.Writer)
+	if !ok {
+		bw = bufio.NewWriter(w)
+	}
+	return checksumWriter{w: bw}
+}
+
+func (w *checksumWriter) write(p []byte) error {
+	w.crc = crc32.Update(w.crc, castagnoli, p)
+	_, err := w.w.Write(p)
+	return err
+}
+
+// finish writes the checksum, resets it for the next snapshot and flushes.
+func (w *checksumWriter) finish() error {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], w.crc)
+	w.crc = 0
+	if _, err := w.w.Write(buf[:]); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+// checksumReader is the reading counterpart of checksumWriter.
+type checksumReader struct {
+	r      *bufio.Reader
+	crc    uint32
+	buf    []byte
+	varint [binary.MaxVarintLen64]byte
+}
+
+func newChecksumReader(r io.Reader) checksumReader {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return checksumReader{r: br}
+}
+
+// read returns the next n bytes, which are only valid until the next read.
+func (r *checksumReader) read(n int) ([]byte, error) {
+	r.buf = slices.Grow(r.buf[:0], n)[:n]
+	if _, err := io.ReadFull(r.r, r.buf); err != nil {
+		return nil, err
+	}
+	r.crc = crc32.Update(r.crc, castagnoli, r.buf)
+	return r.buf, nil
+}
+
+// readLarge is like read, but does not trust n to allocate the buffer upfront,
+// so a corrupt length fails with an unexpected EOF rather than a huge allocation.
+func (r *checksumReader) readLarge(n uint64) ([]byte, error) {
+	const chunk = 64 << 10
+	r.buf = r.buf[:0]
+	for n > 0 {
+		size := int(min(n, chunk))
+		start := len(r.buf)
+		r.buf = slices.Grow(r.buf, size)[:start+size]
+		if _, err := io.ReadFull(r.r, r.buf[start:]); err != nil {
+			return nil, noEOF(err)
+		}
+		n -= uint64(size)
+	}
+	r.crc = crc32.Update(r.crc, castagnoli, r.buf)
+	return r.buf, nil
+}
+
+func (r *checksumReader) readUvarint() (uint64, error) {
+	buf := r.varint[:0]
+	for {
+		b, err := r.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		buf = append(buf, b)
+		if b < 0x80 || len(buf) == len(r.varint) {
+			break
+		}
+	}
+	r.crc = crc32.Update(r.crc, castagnoli, buf)
+	v, n := binary.Uvarint(buf)
+	if n <= 0 {
+		return 0, fmt.Errorf("%w: malformed length", ErrInvalidSnapshot)
+	}
+	return v, nil
+}
+
+// verify reads the checksum and compares it with the one computed so far, then resets it.
+func (r *checksumReader) verify() error {
+	crc := r.crc
+	r.crc = 0
+	var buf [4]byte
+	if _, err := io.ReadFull(r.r, buf[:]); err != nil {
+		return err
+	}
+	if binary.LittleEndian.Uint32(buf[:]) != crc {
+		return fmt.Errorf("%w: checksum mismatch", ErrInvalidSnapshot)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package ordmap
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"math/bits"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var intCodec = BuiltinCodec[int]{IntCodec[int]{}}
+
+func TestSnapshot(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 100, 1000} {
+		tree, _ := randomTree(n, 10*n+1)
+		data, err := MarshalSnapshot(tree, intCodec, IntCodec[int]{})
+		require.NoError(t, err)
+		decoded, err := UnmarshalSnapshot(data, intCodec, IntCodec[int]{})
+		require.NoError(t, err)
+		validateHeight(t, decoded)
+		validateOrdered(t, decoded)
+		require.Equal(t, tree.Entries(), decoded.Entries())
+		require.Equal(t, bits.Len(uint(tree.Len())), decoded.height(), "perfectly balanced")
+	}
+
+	m := NewBuiltin[string, []string]().Insert("b", []string{"x", "y"}).Insert("a", nil)
+	data, err := MarshalSnapshotBuiltin(m, StringCodec[string]{}, JSONCodec[[]string]{})
+	require.NoError(t, err)
+	decoded, err := UnmarshalSnapshotBuiltin(data, StringCodec[string]{}, JSONCodec[[]string]{})
+	require.NoError(t, err)
+	require.Equal(t, m.Entries(), decoded.Entries())
+}
+
+func TestSnapshotStream(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, intCodec, StringCodec[string]{})
+	var versions []*Node[Builtin[int], string]
+	var tree *Node[Builtin[int], string]
+	for i := range 5 {
+		tree = tree.Insert(Builtin[int]{i}, string(rune('a'+i)))
+		versions = append(versions, tree)
+		require.NoError(t, enc.Encode(tree))
+	}
+
+	dec := NewDecoder(&buf, intCodec, StringCodec[string]{})
+	for _, version := range versions {
+		decoded, err := dec.Decode()
+		require.NoError(t, err)
+		require.Equal(t, version.Entries(), decoded.Entries())
+	}
+	_, err := dec.Decode()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestSnapshotCorrupt(t *testing.T) {
+	tree, _ := randomTree(50, 100)
+	data, err := MarshalSnapshot(tree, intCodec, IntCodec[int]{})
+	require.NoError(t, err)
+
+	for i := range data {
+		truncated, err := UnmarshalSnapshot(data[:i], intCodec, IntCodec[int]{})
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF, "truncated to %v", i)
+		require.Nil(t, truncated)
+
+		// flipping any bit is detected one way or another
+		corrupt := bytes.Clone(data)
+		corrupt[i] ^= 1 << (i % 8)
+		_, err = UnmarshalSnapshot(corrupt, intCodec, IntCodec[int]{})
+		require.Error(t, err, "corrupted at %v", i)
+	}
+
+	_, err = UnmarshalSnapshot(append(bytes.Clone(data), 0), intCodec, IntCodec[int]{})
+	require.ErrorIs(t, err, ErrInvalidSnapshot)
+
+	corrupt := bytes.Clone(data)
+	corrupt[len(corrupt)-1]++
+	_, err = UnmarshalSnapshot(corrupt, intCodec, IntCodec[int]{})
+	require.ErrorIs(t, err, ErrInvalidSnapshot)
+
+	corrupt = bytes.Clone(data)
+	corrupt[len(snapshotMagic)] = snapshotVersion + 1
+	_, err = UnmarshalSnapshot(corrupt, intCodec, IntCodec[int]{})
+	require.ErrorContains(t, err, "unsupported version 2")
+
+	// entries out of order are rejected even with a valid checksum
+	var buf bytes.Buffer
+	w := newChecksumWriter(&buf)
+	require.NoError(t, w.write([]byte{'o', 'r', 'd', 'm', snapshotVersion, 2, 1, 4, 1, 0, 1, 2, 1, 0}))
+	require.NoError(t, w.finish())
+	_, err = UnmarshalSnapshot(buf.Bytes(), intCodec, IntCodec[int]{})
+	require.ErrorContains(t, err, "keys out of order")
+
+	// a huge length fails without allocating it
+	buf.Reset()
+	w = newChecksumWriter(&buf)
+	require.NoError(t, w.write([]byte{'o', 'r', 'd', 'm', snapshotVersion, 1, 0xff, 0xff, 0xff, 0xff, 0x7f}))
+	_, err = UnmarshalSnapshot(buf.Bytes(), intCodec, IntCodec[int]{})
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	tree, _ := randomTree(10000, 20000)
+	require.ErrorContains(t, NewEncoder(failingWriter{}, intCodec, IntCodec[int]{}).Encode(tree), "disk full")
+
+	_, err := MarshalSnapshotBuiltin(NewBuiltin[int, float64]().Insert(1, math.Inf(1)), IntCodec[int]{}, JSONCodec[float64]{})
+	require.Error(t, err)
+
+	data, err := MarshalSnapshot(tree, intCodec, IntCodec[int]{})
+	require.NoError(t, err)
+	_, err = UnmarshalSnapshot(data, intCodec, IntCodec[int8]{})
+	require.Error(t, err, "values do not fit")
+}
+
+func codecRoundTrip[T any](t *testing.T, codec Codec[T], values ...T) {
+	t.Helper()
+	for _, v := range values {
+		data, err := codec.Append([]byte("prefix"), v)
+		require.NoError(t, err)
+		decoded, err := codec.Decode(data[len("prefix"):])
+		require.NoError(t, err)
+		require.Equal(t, v, decoded)
+	}
+}
+
+func TestCodecs(t *testing.T) {
+	codecRoundTrip(t, IntCodec[int8]{}, 0, -1, math.MinInt8, math.MaxInt8)
+	codecRoundTrip(t, UintCodec[uint16]{}, 0, math.MaxUint16)
+	codecRoundTrip(t, FloatCodec[float32]{}, 0, -1.5, float32(math.Inf(-1)))
+	codecRoundTrip(t, BytesCodec{}, []byte("abc"))
+	codecRoundTrip(t, StringCodec[string]{}, "", "abc")
+	codecRoundTrip(t, JSONCodec[map[string]int]{}, map[string]int{"a": 1})
+
+	_, err := IntCodec[int8]{}.Decode([]byte{0x80, 0x04}) // 256
+	require.Error(t, err)
+	_, err = UintCodec[uint8]{}.Decode([]byte{0x80, 0x02}) // 256
+	require.Error(t, err)
+	_, err = UintCodec[uint8]{}.Decode([]byte{0x01, 0x01})
+	require.Error(t, err)
+	_, err = FloatCodec[float64]{}.Decode([]byte{1})
+	require.Error(t, err)
+
+	// decoded bytes do not alias the input, which is reused
+	input := []byte("abc")
+	decoded, _ := BytesCodec{}.Decode(input)
+	input[0] = 'x'
+	require.Equal(t, []byte("abc"), decoded)
+}
+
+func BenchmarkSnapshot(b *testing.B) {
+	var tree *Node[Builtin[int], int]
+	for i := range 100000 {
+		tree = tree.Insert(Builtin[int]{i}, i)
+	}
+	data, err := MarshalSnapshot(tree, intCodec, IntCodec[int]{})
+	require.NoError(b, err)
+	b.Run("Encode", func(b *testing.B) {
+		b.ReportAllocs()
+		enc := NewEncoder(io.Discard, intCodec, IntCodec[int]{})
+		for range b.N {
+			enc.Encode(tree)
+		}
+	})
+	b.Run("Decode", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			UnmarshalSnapshot(data, intCodec, IntCodec[int]{})
+		}
+	})
+}
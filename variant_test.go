@@ -0,0 +1,389 @@
+package ordmap
+
+import (
+	"cmp"
+	"iter"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// intMap is the API shared by all map types, instantiated with int keys and values.
+// M is the map type itself.
+type intMap[M any] interface {
+	Get(key int) (int, bool)
+	Insert(key, value int) M
+	InsertFunc(key, value int, eq func(old, new int) bool) M
+	Update(key int, fn func(old int, exists bool) (int, bool)) M
+	Remove(key int) M
+	Split(key int) (M, int, bool, M)
+	RemoveRange(lo, hi int, opts RangeOptions) M
+	TruncateBefore(key int) M
+	TruncateAfter(key int) M
+	Len() int
+	Entries() []Entry[int, int]
+	Min() *Entry[int, int]
+	Max() *Entry[int, int]
+	Floor(key int) *Entry[int, int]
+	Ceiling(key int) *Entry[int, int]
+	Lower(key int) *Entry[int, int]
+	Higher(key int) *Entry[int, int]
+	PopMin() (*Entry[int, int], M)
+	PopMax() (*Entry[int, int], M)
+	DeleteMin() M
+	DeleteMax() M
+	At(i int) *Entry[int, int]
+	IndexOf(key int) (int, bool)
+	Rank(key int) int
+	All() iter.Seq2[int, int]
+	Backward() iter.Seq2[int, int]
+	From(k int) iter.Seq2[int, int]
+	BackwardFrom(k int) iter.Seq2[int, int]
+	Range(lo, hi int, opts RangeOptions) iter.Seq2[int, int]
+	BackwardRange(lo, hi int, opts RangeOptions) iter.Seq2[int, int]
+	Keys() iter.Seq[int]
+	Values() iter.Seq[int]
+	KeysSlice() []int
+	ValuesSlice() []int
+	KeysRange(lo, hi int, opts RangeOptions) iter.Seq[int]
+	ValuesRange(lo, hi int, opts RangeOptions) iter.Seq[int]
+}
+
+type intCursor interface {
+	Valid() bool
+	Key() int
+	Value() int
+	First() bool
+	Last() bool
+	Seek(key int) bool
+	SeekBackward(key int) bool
+	Next() bool
+	Prev() bool
+}
+
+type intBuilder[M any] interface {
+	Get(key int) (int, bool)
+	Len() int
+	Insert(key, value int)
+	Remove(key int)
+	Freeze() M
+}
+
+// variant describes a map type to be checked against *Node by testVariant,
+// providing the parts of its API that differ in shape between the types.
+type variant[M intMap[M]] struct {
+	empty        func() M
+	fromSorted   func(entries []Entry[int, int]) M
+	fromSeq      func(seq iter.Seq2[int, int]) M
+	join         func(left, right M) M
+	union        func(a, b M, resolve func(k, va, vb int) int) M
+	intersection func(a, b M, resolve func(k, va, vb int) int) M
+	difference   func(a, b M) M
+	cursor       func(m M) intCursor
+	builder      func(m M) intBuilder[M]
+	// validate checks the invariants of the tree behind m.
+	validate func(t *testing.T, m M)
+	// root returns the root node of m, to check that maps are returned unchanged.
+	root func(m M) any
+}
+
+// unwrapEntries converts entries of a Node keyed by Builtin for comparison with other map types.
+func unwrapEntries(entries []Entry[Builtin[int], int]) []Entry[int, int] {
+	unwrapped := make([]Entry[int, int], len(entries))
+	for i, e := range entries {
+		unwrapped[i] = Entry[int, int]{e.K.value, e.V}
+	}
+	return unwrapped
+}
+
+func collectEntries(seq iter.Seq2[int, int]) []Entry[int, int] {
+	var entries []Entry[int, int]
+	for k, v := range seq {
+		entries = append(entries, Entry[int, int]{k, v})
+	}
+	return entries
+}
+
+// testVariant runs the map type described by v through the same operations as a *Node
+// and requires identical results.
+func testVariant[M intMap[M]](t *testing.T, v variant[M]) {
+	fromModel := func(model map[int]int) M {
+		return v.fromSorted(unwrapEntries(modelEntries(model)))
+	}
+
+	t.Run("Model", func(t *testing.T) {
+		m := v.empty()
+		var tree *Node[Builtin[int], int]
+		N := 500
+		for range N * 3 {
+			k := rand.Intn(N)
+			switch rand.Intn(4) {
+			case 0:
+				m = m.Remove(k)
+				tree = tree.Remove(Builtin[int]{k})
+			case 1:
+				m = m.Update(k, func(old int, exists bool) (int, bool) { return old + 1, old%3 != 2 })
+				tree = tree.Update(Builtin[int]{k}, func(old int, exists bool) (int, bool) { return old + 1, old%3 != 2 })
+			default:
+				v := rand.Int()
+				m = m.Insert(k, v)
+				tree = tree.Insert(Builtin[int]{k}, v)
+			}
+		}
+		v.validate(t, m)
+		entries := unwrapEntries(tree.Entries())
+		require.Equal(t, entries, m.Entries())
+		require.Equal(t, tree.Len(), m.Len())
+
+		for k := -1; k <= N; k++ {
+			value, ok := m.Get(k)
+			expectedValue, expectedOk := tree.Get(Builtin[int]{k})
+			require.Equal(t, expectedOk, ok)
+			require.Equal(t, expectedValue, value)
+
+			index, ok := m.IndexOf(k)
+			expectedIndex, expectedOk := tree.IndexOf(Builtin[int]{k})
+			require.Equal(t, expectedOk, ok)
+			require.Equal(t, expectedIndex, index)
+			require.Equal(t, tree.Rank(Builtin[int]{k}), m.Rank(k))
+
+			require.Equal(t, unwrapEntry(tree.Floor(Builtin[int]{k})), m.Floor(k))
+			require.Equal(t, unwrapEntry(tree.Ceiling(Builtin[int]{k})), m.Ceiling(k))
+			require.Equal(t, unwrapEntry(tree.Lower(Builtin[int]{k})), m.Lower(k))
+			require.Equal(t, unwrapEntry(tree.Higher(Builtin[int]{k})), m.Higher(k))
+
+			from := collectEntries(m.From(k))
+			backwardFrom := collectEntries(m.BackwardFrom(k))
+			slices.Reverse(backwardFrom)
+			pivot, _ := slices.BinarySearchFunc(entries, k, func(e Entry[int, int], k int) int { return cmp.Compare(e.K, k) })
+			require.Equal(t, entries[pivot:], append([]Entry[int, int]{}, from...))
+			if pivot < len(entries) && entries[pivot].K == k {
+				pivot++
+			}
+			require.Equal(t, entries[:pivot], append([]Entry[int, int]{}, backwardFrom...))
+		}
+		for i := -1; i <= m.Len(); i++ {
+			require.Equal(t, unwrapEntry(tree.At(i)), m.At(i))
+		}
+		require.Equal(t, unwrapEntry(tree.Min()), m.Min())
+		require.Equal(t, unwrapEntry(tree.Max()), m.Max())
+
+		backward := collectEntries(m.Backward())
+		slices.Reverse(backward)
+		require.Equal(t, entries, collectEntries(m.All()))
+		require.Equal(t, entries, backward)
+	})
+
+	t.Run("KeysValues", func(t *testing.T) {
+		tree, model := randomTree(100, 200)
+		m := fromModel(model)
+		require.Equal(t, unwrapEntries(tree.Entries()), m.Entries())
+		var keys, values []int
+		for k, v := range m.All() {
+			keys = append(keys, k)
+			values = append(values, v)
+		}
+		require.Equal(t, keys, m.KeysSlice())
+		require.Equal(t, values, m.ValuesSlice())
+		require.Equal(t, keys, slices.Collect(m.Keys()))
+		require.Equal(t, values, slices.Collect(m.Values()))
+
+		keys, values = nil, nil
+		for k, v := range m.Range(50, 150, RangeOptions{}) {
+			keys = append(keys, k)
+			values = append(values, v)
+		}
+		require.Equal(t, keys, slices.Collect(m.KeysRange(50, 150, RangeOptions{})))
+		require.Equal(t, values, slices.Collect(m.ValuesRange(50, 150, RangeOptions{})))
+	})
+
+	t.Run("Range", func(t *testing.T) {
+		tree, model := randomTree(100, 200)
+		m := fromModel(model)
+		bounds := []Bound{Inclusive, Exclusive, Unbounded}
+		for _, loBound := range bounds {
+			for _, hiBound := range bounds {
+				opts := RangeOptions{Lo: loBound, Hi: hiBound}
+				for lo := -1; lo <= 201; lo += 7 {
+					for hi := lo - 3; hi <= 201; hi += 5 {
+						var expected []Entry[int, int]
+						for k, v := range tree.Range(Builtin[int]{lo}, Builtin[int]{hi}, opts) {
+							expected = append(expected, Entry[int, int]{k.value, v})
+						}
+						backward := collectEntries(m.BackwardRange(lo, hi, opts))
+						slices.Reverse(backward)
+						require.Equal(t, expected, collectEntries(m.Range(lo, hi, opts)), "%v [%v, %v]", opts, lo, hi)
+						require.Equal(t, expected, backward, "%v [%v, %v]", opts, lo, hi)
+
+						removed := m.RemoveRange(lo, hi, opts)
+						v.validate(t, removed)
+						require.Equal(t, unwrapEntries(tree.RemoveRange(Builtin[int]{lo}, Builtin[int]{hi}, opts).Entries()), removed.Entries())
+						if removed.Len() == m.Len() {
+							require.Same(t, v.root(m), v.root(removed))
+						}
+					}
+				}
+			}
+		}
+		for k := -1; k <= 201; k++ {
+			require.Equal(t, unwrapEntries(tree.TruncateBefore(Builtin[int]{k}).Entries()), m.TruncateBefore(k).Entries())
+			require.Equal(t, unwrapEntries(tree.TruncateAfter(Builtin[int]{k}).Entries()), m.TruncateAfter(k).Entries())
+		}
+	})
+
+	t.Run("SetOperations", func(t *testing.T) {
+		sum := func(k int, va, vb int) int {
+			return va + vb
+		}
+		for range 20 {
+			a, modelA := randomTree(rand.Intn(200), 300)
+			b, modelB := randomTree(rand.Intn(200), 300)
+			ma, mb := fromModel(modelA), fromModel(modelB)
+
+			union := v.union(ma, mb, sum)
+			intersection := v.intersection(ma, mb, sum)
+			difference := v.difference(ma, mb)
+			v.validate(t, union)
+			v.validate(t, intersection)
+			v.validate(t, difference)
+			builtinSum := unwrapResolve[int, int](sum)
+			require.Equal(t, unwrapEntries(Union(a, b, builtinSum).Entries()), union.Entries())
+			require.Equal(t, unwrapEntries(Intersection(a, b, builtinSum).Entries()), intersection.Entries())
+			require.Equal(t, unwrapEntries(Difference(a, b).Entries()), difference.Entries())
+			require.Equal(t, unwrapEntries(Union(a, b, nil).Entries()), v.union(ma, mb, nil).Entries())
+
+			k := rand.Intn(300)
+			left, value, found, right := ma.Split(k)
+			v.validate(t, left)
+			v.validate(t, right)
+			expectedValue, expectedFound := modelA[k]
+			require.Equal(t, expectedFound, found)
+			require.Equal(t, expectedValue, value)
+			joined := v.join(left, right)
+			v.validate(t, joined)
+			require.Equal(t, ma.Remove(k).Entries(), joined.Entries())
+		}
+	})
+
+	t.Run("Pop", func(t *testing.T) {
+		_, model := randomTree(100, 200)
+		m := fromModel(model)
+		entries := m.Entries()
+		for i := range entries {
+			min, rest := m.PopMin()
+			require.Equal(t, entries[i], *min)
+			v.validate(t, rest)
+			require.Equal(t, rest.Entries(), m.DeleteMin().Entries())
+			m = rest
+		}
+		min, rest := m.PopMin()
+		require.Nil(t, min)
+		require.Equal(t, 0, rest.Len())
+
+		m = fromModel(model)
+		for i := len(entries) - 1; i >= 0; i-- {
+			max, rest := m.PopMax()
+			require.Equal(t, entries[i], *max)
+			v.validate(t, rest)
+			require.Equal(t, rest.Entries(), m.DeleteMax().Entries())
+			m = rest
+		}
+		max, _ := m.PopMax()
+		require.Nil(t, max)
+	})
+
+	t.Run("NoopPreservesPointer", func(t *testing.T) {
+		_, model := randomTree(100, 200)
+		m := fromModel(model)
+		require.Same(t, v.root(m), v.root(m.Remove(-1)))
+		k := m.Min().K
+		require.Same(t, v.root(m), v.root(m.InsertFunc(k, model[k], func(old, new int) bool { return old == new })))
+		require.Same(t, v.root(m), v.root(m.Update(-1, func(old int, exists bool) (int, bool) { return 0, false })))
+	})
+
+	t.Run("Cursor", func(t *testing.T) {
+		_, model := randomTree(300, 1000)
+		m := fromModel(model)
+		entries := m.Entries()
+		c := v.cursor(m)
+		require.False(t, c.Valid())
+
+		var seen []Entry[int, int]
+		for ok := c.First(); ok; ok = c.Next() {
+			seen = append(seen, Entry[int, int]{c.Key(), c.Value()})
+		}
+		require.Equal(t, entries, seen)
+		seen = nil
+		for ok := c.Last(); ok; ok = c.Prev() {
+			seen = append(seen, Entry[int, int]{c.Key(), c.Value()})
+		}
+		slices.Reverse(seen)
+		require.Equal(t, entries, seen)
+
+		for k := -1; k <= 1001; k++ {
+			if e := m.Ceiling(k); e != nil {
+				require.True(t, c.Seek(k))
+				require.Equal(t, *e, Entry[int, int]{c.Key(), c.Value()})
+			} else {
+				require.False(t, c.Seek(k))
+			}
+			if e := m.Floor(k); e != nil {
+				require.True(t, c.SeekBackward(k))
+				require.Equal(t, *e, Entry[int, int]{c.Key(), c.Value()})
+			} else {
+				require.False(t, c.SeekBackward(k))
+			}
+		}
+	})
+
+	t.Run("Builder", func(t *testing.T) {
+		source, model := randomTree(300, 500)
+		m := fromModel(model)
+		sourceEntries := m.Entries()
+
+		b := v.builder(m)
+		N := 500
+		for range N * 3 {
+			k := rand.Intn(N)
+			if rand.Float64() < 0.6 {
+				v := rand.Int()
+				b.Insert(k, v)
+				model[k] = v
+			} else {
+				b.Remove(k)
+				delete(model, k)
+			}
+			require.Equal(t, len(model), b.Len())
+		}
+		for k, v := range model {
+			value, ok := b.Get(k)
+			require.True(t, ok)
+			require.Equal(t, v, value)
+		}
+		frozen := b.Freeze()
+		v.validate(t, frozen)
+		require.Equal(t, unwrapEntries(modelEntries(model)), frozen.Entries())
+		require.Equal(t, sourceEntries, m.Entries()) // source untouched
+		require.Equal(t, unwrapEntries(source.Entries()), m.Entries())
+	})
+
+	t.Run("Constructors", func(t *testing.T) {
+		entries := []Entry[int, int]{{3, 0}, {1, 1}, {2, 2}, {1, 3}}
+		m := v.fromSeq(func(yield func(int, int) bool) {
+			for _, e := range entries {
+				if !yield(e.K, e.V) {
+					return
+				}
+			}
+		})
+		v.validate(t, m)
+		require.Equal(t, []Entry[int, int]{{1, 3}, {2, 2}, {3, 0}}, m.Entries())
+
+		sorted := v.fromSorted(m.Entries())
+		v.validate(t, sorted)
+		require.Equal(t, m.Entries(), sorted.Entries())
+		require.Equal(t, 0, v.empty().Len())
+	})
+}